@@ -5,27 +5,48 @@ import (
 	"encoding/csv"
 	"errors"
 	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Arguments struct {
 	Comment rune
+
+	// Shell enables POSIX sh grammar instead of space separated csv.
+	Shell bool
+
+	// FuncLookup enables expansion of $VAR, ${VAR}, ${VAR:-default} and ${VAR:?message}
+	// by looking up variables with it, e.g. os.LookupEnv. Expansions are not performed
+	// inside single quotes in shell grammar, and unset variables expand to empty.
+	FuncLookup func(name string) (string, bool)
 }
 
 func (a *Arguments) Parse(str string) ([]string, error) {
-	cr := csv.NewReader(bytes.NewBufferString(str))
-	cr.Comma = ' '
-	cr.Comment = a.Comment
-	cr.FieldsPerRecord = -1
-	cr.LazyQuotes = true
-	cr.TrimLeadingSpace = true
-	cr.ReuseRecord = false
+	if a.Shell {
+		return a.parseShell(str)
+	}
+
+	if a.FuncLookup != nil {
+		tokens, err := a.ParseTokens(str)
+		if err != nil {
+			return nil, err
+		}
+		args := make([]string, 0, len(tokens))
+		for _, token := range tokens {
+			args = append(args, token.Value)
+		}
+		return args, nil
+	}
+
+	cr := a.newCSVReader(str)
 
 	args, err := cr.Read()
 	if err != nil {
 		if errors.Is(err, io.EOF) {
 			return []string{}, nil
 		}
-		return nil, newParseError(err)
+		return nil, newCSVParseError(err, str)
 	}
 
 	if l := len(args); l > 0 && args[l-1] == "" {
@@ -35,7 +56,52 @@ func (a *Arguments) Parse(str string) ([]string, error) {
 	return args, nil
 }
 
+// ParseTokens parses str like Parse, and returns the arguments with their positions in str.
+func (a *Arguments) ParseTokens(str string) ([]ArgumentToken, error) {
+	if a.Shell {
+		return a.parseShellTokens(str)
+	}
+
+	cr := a.newCSVReader(str)
+
+	args, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return []ArgumentToken{}, nil
+		}
+		return nil, newCSVParseError(err, str)
+	}
+
+	if l := len(args); l > 0 && args[l-1] == "" {
+		args = args[:l-1]
+	}
+
+	pos := getCSVRecordStart(str, a.Comment)
+	tokens := make([]ArgumentToken, 0, len(args))
+	for _, arg := range args {
+		start := getCSVFieldStart(str, pos)
+		end, _ := getCSVFieldEnd(str, start)
+		pos = end + 1
+		if a.FuncLookup != nil {
+			arg, err = a.expandPlain(arg)
+			if err != nil {
+				if e, ok := err.(*ParseError); ok {
+					err = newParseErrorAt(e.err, str, start)
+				}
+				return nil, err
+			}
+		}
+		tokens = append(tokens, newArgumentToken(str, arg, start, end))
+	}
+
+	return tokens, nil
+}
+
 func (a *Arguments) Format(args ...string) (string, error) {
+	if a.Shell {
+		return a.formatShell(args...), nil
+	}
+
 	buf := bytes.NewBuffer(make([]byte, 0, 4096))
 	cw := csv.NewWriter(buf)
 	cw.Comma = ' '
@@ -47,3 +113,130 @@ func (a *Arguments) Format(args ...string) (string, error) {
 
 	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
 }
+
+func (a *Arguments) newCSVReader(str string) *csv.Reader {
+	cr := csv.NewReader(bytes.NewBufferString(str))
+	cr.Comma = ' '
+	cr.Comment = a.Comment
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = false
+	return cr
+}
+
+// ArgumentToken is a parsed argument with its position in the input.
+type ArgumentToken struct {
+	// Value is the argument after quote removal.
+	Value string
+
+	// Raw is the argument as it is written in the input.
+	Raw string
+
+	// Start and End are the byte offsets of Raw in the input.
+	Start int
+	End   int
+
+	// RuneStart and RuneEnd are the rune offsets of Raw in the input.
+	RuneStart int
+	RuneEnd   int
+}
+
+func newArgumentToken(str string, value string, start, end int) ArgumentToken {
+	runeStart := utf8.RuneCountInString(str[:start])
+	return ArgumentToken{
+		Value:     value,
+		Raw:       str[start:end],
+		Start:     start,
+		End:       end,
+		RuneStart: runeStart,
+		RuneEnd:   runeStart + utf8.RuneCountInString(str[start:end]),
+	}
+}
+
+func newCSVParseError(err error, str string) error {
+	var e *csv.ParseError
+	if errors.As(err, &e) && e.Line > 0 {
+		if lineStarts := getLineStarts(str); e.Line <= len(lineStarts) {
+			offset := lineStarts[e.Line-1]
+			if e.Column > 0 {
+				offset += e.Column - 1
+			}
+			return newParseErrorAt(err, str, offset)
+		}
+	}
+	return newParseError(err)
+}
+
+func getLineStarts(str string) []int {
+	result := []int{0}
+	for idx := 0; ; {
+		i := strings.IndexByte(str[idx:], '\n')
+		if i < 0 {
+			break
+		}
+		idx += i + 1
+		result = append(result, idx)
+	}
+	return result
+}
+
+// getCSVRecordStart returns the offset of the first record in str, skipping empty and comment lines like csv.Reader.
+func getCSVRecordStart(str string, comment rune) int {
+	pos := 0
+	for pos < len(str) {
+		line := str[pos:]
+		if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+			line = line[:idx+1]
+		}
+		if r, _ := utf8.DecodeRuneInString(line); (comment != 0 && r == comment) || line == "\n" || line == "\r\n" {
+			pos += len(line)
+			continue
+		}
+		break
+	}
+	return pos
+}
+
+// getCSVFieldStart returns the offset of the csv field which follows pos, skipping leading white space.
+func getCSVFieldStart(str string, pos int) int {
+	for pos < len(str) {
+		r, size := utf8.DecodeRuneInString(str[pos:])
+		if r == '\n' || !unicode.IsSpace(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+// getCSVFieldEnd returns the end offset of the csv field which starts at start, accepting lazy quotes.
+// It reports whether a quoted field is closed.
+func getCSVFieldEnd(str string, start int) (int, bool) {
+	isFieldEnd := func(i int) bool {
+		return i >= len(str) || str[i] == ' ' || str[i] == '\n' || str[i] == '\r'
+	}
+
+	if start >= len(str) || str[start] != '"' {
+		end := start
+		for !isFieldEnd(end) {
+			end++
+		}
+		return end, true
+	}
+
+	for i := start + 1; ; {
+		idx := strings.IndexByte(str[i:], '"')
+		if idx < 0 {
+			return len(strings.TrimRight(str, "\r\n")), false
+		}
+		i += idx + 1
+		if i < len(str) && str[i] == '"' {
+			i++
+			continue
+		}
+		if isFieldEnd(i) {
+			return i, true
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package xstrings
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+)
+
+func TestArgumentsShellParse(t *testing.T) {
+	a := &Arguments{Comment: '#', Shell: true}
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{}},
+		{" a \t b\nc ", []string{"a", "b", "c"}},
+		{`'a b'"c d"e\ f`, []string{"a bc de f"}},
+		{`'' ""`, []string{"", ""}},
+		{`"a\"b\$c\d"`, []string{`a"b$c\d`}},
+		{`$'a\tb\x41\u00e7\''`, []string{"a\tbA\u00e7'"}},
+		{"a \\\nb\\\nc", []string{"a", "bc"}},
+		{"a #b c\nd", []string{"a", "d"}},
+		{"a#b", []string{"a#b"}},
+	}
+	for _, c := range cases {
+		got, err := a.Parse(c.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Parse(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestArgumentsShellParseError(t *testing.T) {
+	a := &Arguments{Shell: true}
+	for _, in := range []string{`'a`, `"a`, `$'a`, `a\`} {
+		if _, err := a.Parse(in); err == nil {
+			t.Errorf("Parse(%q) expected error", in)
+		}
+	}
+}
+
+func TestArgumentsShellFormat(t *testing.T) {
+	a := &Arguments{Comment: '#', Shell: true}
+	cases := []struct {
+		in   []string
+		want string
+	}{
+		{[]string{"a", "b/c.d"}, "a b/c.d"},
+		{[]string{"", "a b"}, "'' 'a b'"},
+		{[]string{"#a", "a#"}, "'#a' 'a#'"},
+		{[]string{"$HOME", "`x`"}, "'$HOME' '`x`'"},
+		{[]string{"it's"}, `"it's"`},
+		{[]string{"it's!"}, `'it'\''s!'`},
+		{[]string{"a\nb", "\xff"}, `$'a\nb' $'\xff'`},
+	}
+	for _, c := range cases {
+		got, err := a.Format(c.in...)
+		if err != nil {
+			t.Errorf("Format(%q) error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Format(%q) = %s, want %s", c.in, got, c.want)
+		}
+	}
+}
+
+type shellWords []string
+
+func (shellWords) Generate(rand *rand.Rand, size int) reflect.Value {
+	alphabet := []rune("ab 09\t\n'\"\\$`!#%~*?;&|<>(){}[]=\u00e7\u011f\u00a0\u200b\u4e16\U0001f600\x00\x1b\x7f\ufffd")
+	words := make(shellWords, rand.Intn(size+1))
+	for i := range words {
+		buf := make([]byte, 0, size)
+		for j, k := 0, rand.Intn(size+1); j < k; j++ {
+			if rand.Intn(16) == 0 {
+				buf = append(buf, byte(rand.Intn(256)))
+				continue
+			}
+			var p [utf8.UTFMax]byte
+			if rand.Intn(4) == 0 {
+				buf = append(buf, p[:utf8.EncodeRune(p[:], rune(rand.Intn(utf8.MaxRune+1)))]...)
+				continue
+			}
+			buf = append(buf, p[:utf8.EncodeRune(p[:], alphabet[rand.Intn(len(alphabet))])]...)
+		}
+		words[i] = string(buf)
+	}
+	return reflect.ValueOf(words)
+}
+
+func TestArgumentsShellRoundTrip(t *testing.T) {
+	for _, comment := range []rune{0, '#', '%'} {
+		a := &Arguments{Comment: comment, Shell: true}
+		f := func(words shellWords) bool {
+			str, err := a.Format(words...)
+			if err != nil {
+				t.Logf("Format(%q) error: %v", []string(words), err)
+				return false
+			}
+			args, err := a.Parse(str)
+			if err != nil {
+				t.Logf("Parse(%s) error: %v", str, err)
+				return false
+			}
+			if len(args) != len(words) {
+				t.Logf("Parse(%s) = %q, want %q", str, args, []string(words))
+				return false
+			}
+			for i := range args {
+				if args[i] != words[i] {
+					t.Logf("Parse(%s) = %q, want %q", str, args, []string(words))
+					return false
+				}
+			}
+			return true
+		}
+		if err := quick.Check(f, &quick.Config{MaxCount: 2000}); err != nil {
+			t.Errorf("comment %q: %v", comment, err)
+		}
+	}
+}
+
+func TestArgumentsParseTokens(t *testing.T) {
+	for _, shell := range []bool{false, true} {
+		a := &Arguments{Shell: shell}
+		tokens, err := a.ParseTokens(`ç "b c" d`)
+		if err != nil {
+			t.Errorf("shell %v: ParseTokens error: %v", shell, err)
+			continue
+		}
+		want := []ArgumentToken{
+			{Value: "ç", Raw: "ç", Start: 0, End: 2, RuneStart: 0, RuneEnd: 1},
+			{Value: "b c", Raw: `"b c"`, Start: 3, End: 8, RuneStart: 2, RuneEnd: 7},
+			{Value: "d", Raw: "d", Start: 9, End: 10, RuneStart: 8, RuneEnd: 9},
+		}
+		if !reflect.DeepEqual(tokens, want) {
+			t.Errorf("shell %v: ParseTokens = %+v, want %+v", shell, tokens, want)
+		}
+	}
+
+	a := &Arguments{Comment: '#'}
+	tokens, err := a.ParseTokens("#x\n\n a  \"b\nc\" d")
+	if err != nil {
+		t.Fatalf("ParseTokens error: %v", err)
+	}
+	want := []ArgumentToken{
+		{Value: "a", Raw: "a", Start: 5, End: 6, RuneStart: 5, RuneEnd: 6},
+		{Value: "b\nc", Raw: "\"b\nc\"", Start: 8, End: 13, RuneStart: 8, RuneEnd: 13},
+		{Value: "d", Raw: "d", Start: 14, End: 15, RuneStart: 14, RuneEnd: 15},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("ParseTokens = %+v, want %+v", tokens, want)
+	}
+}
+
+func TestArgumentsParseErrorCaret(t *testing.T) {
+	a := &Arguments{Shell: true}
+	_, err := a.Parse("a\n\tb 'c")
+	e, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("Parse error = %v, want *ParseError", err)
+	}
+	if e.Line() != 2 || e.Column() != 4 {
+		t.Errorf("Line, Column = %d, %d, want 2, 4", e.Line(), e.Column())
+	}
+	if want := "\tb 'c\n\t  ^"; e.Caret() != want {
+		t.Errorf("Caret = %q, want %q", e.Caret(), want)
+	}
+}
+
+func TestArgumentsExpansion(t *testing.T) {
+	env := map[string]string{"HOST": "db.local", "EMPTY": ""}
+	a := &Arguments{
+		Shell: true,
+		FuncLookup: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+	}
+
+	got, err := a.Parse(`connect $HOST ${PORT:-5432} '$HOST' "${HOST}:${EMPTY:-x}" ${HOST:-${PORT:?unused}}`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	want := []string{"connect", "db.local", "5432", "$HOST", "db.local:x", "db.local"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %q, want %q", got, want)
+	}
+
+	_, err = a.Parse(`connect ${PORT:?port is required}`)
+	e, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("Parse error = %v, want *ParseError", err)
+	}
+	if e2, ok := e.Unwrap().(*UnresolvedVariableError); !ok || e2.Name() != "PORT" {
+		t.Errorf("Parse error = %v, want *UnresolvedVariableError", err)
+	}
+	if e.Column() != 9 {
+		t.Errorf("Column = %d, want 9", e.Column())
+	}
+}
@@ -0,0 +1,307 @@
+package xstrings
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ArgumentsReader reads argument vectors from a stream, one per logical command.
+// A logical command ends at a newline unless the newline is inside quotes or,
+// in shell grammar, escaped by a trailing backslash.
+// Blank lines and comment lines are skipped.
+type ArgumentsReader struct {
+	arguments *Arguments
+	br        *bufio.Reader
+	line      int
+	startLine int
+	endLine   int
+}
+
+// NewArgumentsReader returns a new ArgumentsReader that reads from r and parses by arguments.
+// If arguments is nil, zero Arguments is used.
+func NewArgumentsReader(r io.Reader, arguments *Arguments) *ArgumentsReader {
+	if arguments == nil {
+		arguments = &Arguments{}
+	}
+	return &ArgumentsReader{
+		arguments: arguments,
+		br:        bufio.NewReader(r),
+	}
+}
+
+// Read reads the next logical command. It returns io.EOF when there are no more commands.
+// The offsets of a returned ParseError are relative to the beginning of the command,
+// whose physical lines can be got by LinePos. The line endings "\r\n" are read as "\n".
+func (r *ArgumentsReader) Read() ([]string, error) {
+	buf := &strings.Builder{}
+	state := &argumentsReaderState{arguments: r.arguments}
+	r.startLine = r.line + 1
+	r.endLine = r.line
+	for {
+		line, err := r.br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if line == "" {
+			if buf.Len() <= 0 {
+				return nil, err
+			}
+			args, e := r.parse(buf.String())
+			if e != nil {
+				return nil, e
+			}
+			if len(args) > 0 {
+				return args, nil
+			}
+			return nil, err
+		}
+		r.line++
+		r.endLine = r.line
+		if strings.HasSuffix(line, "\n") {
+			line = strings.TrimSuffix(line[:len(line)-1], "\r") + "\n"
+		} else {
+			line = strings.TrimSuffix(line, "\r")
+		}
+		buf.WriteString(line)
+
+		if !state.feed(strings.TrimSuffix(line, "\n")) && err == nil {
+			continue
+		}
+		args, e := r.parse(buf.String())
+		if e != nil {
+			return nil, e
+		}
+		if len(args) > 0 {
+			return args, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.Reset()
+		state = &argumentsReaderState{arguments: r.arguments}
+		r.startLine = r.line + 1
+	}
+}
+
+// LinePos returns the first and last physical line numbers of the most recently read command.
+// Numbering of lines starts at 1.
+func (r *ArgumentsReader) LinePos() (startLine, endLine int) {
+	return r.startLine, r.endLine
+}
+
+// Line returns the number of physical lines read so far.
+func (r *ArgumentsReader) Line() int {
+	return r.line
+}
+
+// parse parses the complete command str.
+func (r *ArgumentsReader) parse(str string) ([]string, error) {
+	str = strings.TrimSuffix(str, "\n")
+
+	if r.arguments.Shell {
+		return r.arguments.Parse(str)
+	}
+
+	tokens, err := r.arguments.ParseTokens(str)
+	if err != nil {
+		return nil, err
+	}
+	if l := len(tokens); l > 0 {
+		if _, closed := getCSVFieldEnd(tokens[l-1].Raw, 0); !closed {
+			return nil, newParseErrorAt(ErrUnterminatedQuote, str, tokens[l-1].Start)
+		}
+	}
+	args := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		args = append(args, token.Value)
+	}
+	return args, nil
+}
+
+// argumentsReaderState tracks the quotes and the line continuation of the physical lines of a command,
+// so that the command is parsed only once when it is complete.
+type argumentsReaderState struct {
+	arguments *Arguments
+
+	// contexts is the stack of the open shell quotes and brace expansions: '\'', '"', '$' for $'...' and '{'.
+	contexts []byte
+
+	// inWord reports whether the shell scanner is in a word, where the comment rune isn't special.
+	inWord bool
+
+	// continued reports whether the last line ends with a backslash outside of quotes in shell grammar.
+	continued bool
+
+	// quoted reports whether the last line ends in a quoted csv field.
+	quoted bool
+
+	// failed reports whether a bad substitution is found, which fails the command regardless of the next lines.
+	failed bool
+}
+
+// feed scans line, which doesn't include the line ending, and reports whether the command is complete.
+func (s *argumentsReaderState) feed(line string) bool {
+	if s.arguments.Shell {
+		s.feedShell(line)
+		return s.failed || (!s.continued && !s.inQuotes())
+	}
+	s.feedCSV(line)
+	return !s.quoted
+}
+
+func (s *argumentsReaderState) feedShell(line string) {
+	s.continued = false
+	comment := s.arguments.Comment
+	lookup := s.arguments.FuncLookup != nil
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		_, nextSize := utf8.DecodeRuneInString(line[i+size:])
+		next := line[i+size : i+size+nextSize]
+		top := byte(0)
+		if l := len(s.contexts); l > 0 {
+			top = s.contexts[l-1]
+		}
+
+		switch top {
+		case '\'':
+			if r == '\'' {
+				s.pop()
+			}
+
+		case '$':
+			switch r {
+			case '\\':
+				size += len(next)
+			case '\'':
+				s.pop()
+			}
+
+		case '"':
+			switch {
+			case r == '\\':
+				size += len(next)
+			case r == '"':
+				s.pop()
+			case r == '$' && next == "{" && lookup:
+				if size = s.pushBrace(line[i:]); size <= 0 {
+					return
+				}
+			}
+
+		default:
+			if r == '\\' && next == "" {
+				s.continued = true
+				break
+			}
+			if top == 0 {
+				if unicode.IsSpace(r) {
+					s.inWord = false
+					break
+				}
+				if !s.inWord && comment != 0 && r == comment {
+					return
+				}
+				s.inWord = true
+			}
+			switch {
+			case r == '\\':
+				size += len(next)
+			case r == '\'':
+				s.push('\'')
+			case r == '"':
+				s.push('"')
+			case r == '$' && next == "'" && top == 0:
+				s.push('$')
+				size++
+			case r == '$' && next == "{" && lookup:
+				if size = s.pushBrace(line[i:]); size <= 0 {
+					return
+				}
+			case r == '}' && top == '{':
+				s.pop()
+			}
+		}
+
+		i += size
+	}
+}
+
+func (s *argumentsReaderState) feedCSV(line string) {
+	if r, _ := utf8.DecodeRuneInString(line); !s.quoted && s.arguments.Comment != 0 && r == s.arguments.Comment {
+		return
+	}
+	for i := 0; i < len(line); {
+		if s.quoted {
+			idx := strings.IndexByte(line[i:], '"')
+			if idx < 0 {
+				return
+			}
+			i += idx + 1
+			if i < len(line) && line[i] == '"' {
+				i++
+				continue
+			}
+			if i >= len(line) || line[i] == ' ' || line[i] == '\r' {
+				s.quoted = false
+			}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		if r == '"' {
+			s.quoted = true
+			i++
+			continue
+		}
+		idx := strings.IndexByte(line[i:], ' ')
+		if idx < 0 {
+			return
+		}
+		i += idx + 1
+	}
+}
+
+// pushBrace pushes the brace expansion at the beginning of str, and returns the length of "${name" and
+// the operator. It returns zero and sets failed if the brace expansion is bad, like shellScanner.
+func (s *argumentsReaderState) pushBrace(str string) int {
+	sc := &shellScanner{str: str, pos: 2}
+	sc.scanName()
+	if sc.pos <= 2 || sc.pos >= len(str) {
+		s.failed = true
+		return 0
+	}
+	if str[sc.pos] != '}' {
+		if str[sc.pos] == ':' {
+			sc.pos++
+		}
+		if sc.pos >= len(str) || (str[sc.pos] != '-' && str[sc.pos] != '?') {
+			s.failed = true
+			return 0
+		}
+		sc.pos++
+	}
+	s.push('{')
+	return sc.pos
+}
+
+// inQuotes reports whether the innermost context is a quote. A brace expansion which is not closed
+// at the end of the line is a bad substitution.
+func (s *argumentsReaderState) inQuotes() bool {
+	l := len(s.contexts)
+	return l > 0 && s.contexts[l-1] != '{'
+}
+
+func (s *argumentsReaderState) push(c byte) {
+	s.contexts = append(s.contexts, c)
+}
+
+func (s *argumentsReaderState) pop() {
+	s.contexts = s.contexts[:len(s.contexts)-1]
+}
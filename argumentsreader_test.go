@@ -0,0 +1,145 @@
+package xstrings
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArgumentsReader(t *testing.T) {
+	type command struct {
+		args               []string
+		startLine, endLine int
+	}
+	cases := []struct {
+		arguments *Arguments
+		in        string
+		want      []command
+	}{
+		{
+			&Arguments{Comment: '#', Shell: true},
+			"# comment\n\na b\nc \\\nd 'e\nf'\r\n  \ng",
+			[]command{
+				{[]string{"a", "b"}, 3, 3},
+				{[]string{"c", "d", "e\nf"}, 4, 6},
+				{[]string{"g"}, 8, 8},
+			},
+		},
+		{
+			&Arguments{Shell: true, Comment: '#'},
+			"a \\\r\nb\r\nc 'd\r\n# e' f#g # h '\r\n\"i\\\"\nj\" $'k\\'\nl'\r\nm\\\\\nn\n",
+			[]command{
+				{[]string{"a", "b"}, 1, 2},
+				{[]string{"c", "d\n# e", "f#g"}, 3, 4},
+				{[]string{"i\"\nj", "k'\nl"}, 5, 7},
+				{[]string{"m\\"}, 8, 8},
+				{[]string{"n"}, 9, 9},
+			},
+		},
+		{
+			&Arguments{Shell: true, FuncLookup: func(name string) (string, bool) { return "", false }},
+			"${X:-'a\nb'} \"${Y:-\"c\nd\"}\"\n${Z:-e f}\n",
+			[]command{
+				{[]string{"a\nb", "c\nd"}, 1, 3},
+				{[]string{"e f"}, 4, 4},
+			},
+		},
+		{
+			&Arguments{Comment: '#'},
+			"a \"b\r\n# c\"\"d\" e\r\nf \"g\"\"h\" i\nj \"\nk\"\n",
+			[]command{
+				{[]string{"a", "b\n# c\"d", "e"}, 1, 2},
+				{[]string{"f", "g\"h", "i"}, 3, 3},
+				{[]string{"j", "\nk"}, 4, 5},
+			},
+		},
+		{
+			&Arguments{Comment: '#'},
+			"#c\na \"b\nc\" d\n\ne\n",
+			[]command{
+				{[]string{"a", "b\nc", "d"}, 2, 3},
+				{[]string{"e"}, 5, 5},
+			},
+		},
+	}
+	for _, c := range cases {
+		r := NewArgumentsReader(strings.NewReader(c.in), c.arguments)
+		got := make([]command, 0, len(c.want))
+		for {
+			args, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Errorf("Read(%q) error: %v", c.in, err)
+				break
+			}
+			startLine, endLine := r.LinePos()
+			got = append(got, command{args, startLine, endLine})
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Read(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestArgumentsReaderError(t *testing.T) {
+	cases := []struct {
+		arguments *Arguments
+		in        string
+		endLine   int
+		column    int
+	}{
+		{&Arguments{Shell: true}, "a\nb 'c\nd", 3, 3},
+		{&Arguments{}, "a\nb \"c\nd", 3, 3},
+		{&Arguments{Shell: true}, "a\nb c\\", 2, 4},
+	}
+	for _, c := range cases {
+		r := NewArgumentsReader(strings.NewReader(c.in), c.arguments)
+		if _, err := r.Read(); err != nil {
+			t.Errorf("Read(%q) error: %v", c.in, err)
+			continue
+		}
+		_, err := r.Read()
+		var e *ParseError
+		if !errors.As(err, &e) {
+			t.Errorf("Read(%q) error = %v, want *ParseError", c.in, err)
+			continue
+		}
+		if e.Line() != 1 || e.Column() != c.column {
+			t.Errorf("Read(%q) error position = %d, %d, want 1, %d", c.in, e.Line(), e.Column(), c.column)
+		}
+		if startLine, endLine := r.LinePos(); startLine != 2 || endLine != c.endLine {
+			t.Errorf("Read(%q) LinePos = %d, %d, want 2, %d", c.in, startLine, endLine, c.endLine)
+		}
+	}
+}
+
+type errReader struct {
+	data string
+	err  error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestArgumentsReaderReadError(t *testing.T) {
+	errRead := errors.New("read error")
+	for _, arguments := range []*Arguments{{Shell: true}, {}} {
+		r := NewArgumentsReader(&errReader{"a\nb \"c\nd", errRead}, arguments)
+		if args, err := r.Read(); err != nil || !reflect.DeepEqual(args, []string{"a"}) {
+			t.Errorf("Read = %q, %v, want [a]", args, err)
+		}
+		if _, err := r.Read(); err != errRead {
+			t.Errorf("Read error = %v, want %v", err, errRead)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+package xstrings
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func (a *Arguments) parseShell(str string) ([]string, error) {
+	s := &shellScanner{
+		comment: a.Comment,
+		lookup:  a.FuncLookup,
+		str:     str,
+	}
+
+	tokens, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		args = append(args, token.value)
+	}
+	return args, nil
+}
+
+func (a *Arguments) parseShellTokens(str string) ([]ArgumentToken, error) {
+	s := &shellScanner{
+		comment: a.Comment,
+		lookup:  a.FuncLookup,
+		str:     str,
+	}
+
+	tokens, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ArgumentToken, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, newArgumentToken(str, token.value, token.start, token.end))
+	}
+	return result, nil
+}
+
+func (a *Arguments) expandPlain(str string) (string, error) {
+	s := &shellScanner{
+		lookup: a.FuncLookup,
+		plain:  true,
+		str:    str,
+	}
+	return s.expandPlain()
+}
+
+type shellToken struct {
+	value string
+	start int
+	end   int
+}
+
+// shellScanner splits its input into words according to POSIX sh quoting rules.
+// If lookup is set, parameter expansions are also performed outside single quotes.
+// In plain mode, only parameter expansions are recognized and quotes are literal.
+// While skip is set, the words of unused expansions are scanned without raising errors.
+type shellScanner struct {
+	comment rune
+	lookup  func(name string) (string, bool)
+	plain   bool
+	skip    bool
+	str     string
+	pos     int
+}
+
+func (s *shellScanner) scan() ([]shellToken, error) {
+	tokens := make([]shellToken, 0, 16)
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.str) {
+			break
+		}
+		if r, _ := s.peek(); s.comment != 0 && r == s.comment {
+			s.skipLine()
+			continue
+		}
+		token, err := s.scanWord()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (s *shellScanner) scanWord() (shellToken, error) {
+	token := shellToken{
+		start: s.pos,
+	}
+	buf := make([]byte, 0, 64)
+
+	for s.pos < len(s.str) {
+		r, size := s.peek()
+		if unicode.IsSpace(r) {
+			break
+		}
+
+		var err error
+		switch {
+		case r == '\\':
+			buf, err = s.scanEscape(buf)
+		case r == '\'':
+			buf, err = s.scanSingleQuoted(buf)
+		case r == '"':
+			buf, err = s.scanDoubleQuoted(buf)
+		case r == '$' && strings.HasPrefix(s.str[s.pos+1:], "'"):
+			buf, err = s.scanANSIQuoted(buf)
+		case r == '$' && s.lookup != nil:
+			buf, err = s.scanExpansion(buf)
+		default:
+			buf = append(buf, s.str[s.pos:s.pos+size]...)
+			s.pos += size
+		}
+		if err != nil {
+			return shellToken{}, err
+		}
+	}
+
+	token.value = string(buf)
+	token.end = s.pos
+	return token, nil
+}
+
+func (s *shellScanner) scanEscape(buf []byte) ([]byte, error) {
+	s.pos++
+	if s.pos >= len(s.str) {
+		return nil, newParseErrorAt(ErrTrailingBackslash, s.str, s.pos-1)
+	}
+	_, size := s.peek()
+	if s.str[s.pos] != '\n' {
+		buf = append(buf, s.str[s.pos:s.pos+size]...)
+	}
+	s.pos += size
+	return buf, nil
+}
+
+func (s *shellScanner) scanSingleQuoted(buf []byte) ([]byte, error) {
+	start := s.pos
+	s.pos++
+	idx := strings.IndexByte(s.str[s.pos:], '\'')
+	if idx < 0 {
+		s.pos = len(s.str)
+		return nil, newParseErrorAt(ErrUnterminatedQuote, s.str, start)
+	}
+	buf = append(buf, s.str[s.pos:s.pos+idx]...)
+	s.pos += idx + 1
+	return buf, nil
+}
+
+func (s *shellScanner) scanDoubleQuoted(buf []byte) ([]byte, error) {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.str) {
+		c := s.str[s.pos]
+		switch c {
+		case '"':
+			s.pos++
+			return buf, nil
+		case '$':
+			if s.lookup == nil {
+				buf = append(buf, c)
+				s.pos++
+				break
+			}
+			var err error
+			buf, err = s.scanExpansion(buf)
+			if err != nil {
+				return nil, err
+			}
+		case '\\':
+			if s.pos+1 < len(s.str) {
+				switch next := s.str[s.pos+1]; next {
+				case '$', '`', '"', '\\':
+					buf = append(buf, next)
+					s.pos += 2
+					continue
+				case '\n':
+					s.pos += 2
+					continue
+				}
+			}
+			buf = append(buf, c)
+			s.pos++
+		default:
+			buf = append(buf, c)
+			s.pos++
+		}
+	}
+	return nil, newParseErrorAt(ErrUnterminatedQuote, s.str, start)
+}
+
+func (s *shellScanner) scanANSIQuoted(buf []byte) ([]byte, error) {
+	start := s.pos
+	s.pos += 2
+	for s.pos < len(s.str) {
+		c := s.str[s.pos]
+		switch c {
+		case '\'':
+			s.pos++
+			return buf, nil
+		case '\\':
+			s.pos++
+			if s.pos >= len(s.str) {
+				break
+			}
+			buf = s.scanANSIEscape(buf)
+		default:
+			buf = append(buf, c)
+			s.pos++
+		}
+	}
+	return nil, newParseErrorAt(ErrUnterminatedQuote, s.str, start)
+}
+
+func (s *shellScanner) scanANSIEscape(buf []byte) []byte {
+	c := s.str[s.pos]
+	s.pos++
+	switch c {
+	case 'a':
+		return append(buf, '\a')
+	case 'b':
+		return append(buf, '\b')
+	case 'e', 'E':
+		return append(buf, 0x1b)
+	case 'f':
+		return append(buf, '\f')
+	case 'n':
+		return append(buf, '\n')
+	case 'r':
+		return append(buf, '\r')
+	case 't':
+		return append(buf, '\t')
+	case 'v':
+		return append(buf, '\v')
+	case '\\', '\'', '"', '?':
+		return append(buf, c)
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		s.pos--
+		return append(buf, byte(s.scanDigits(8, 3)))
+	case 'x':
+		if !s.hasDigit(16) {
+			break
+		}
+		return append(buf, byte(s.scanDigits(16, 2)))
+	case 'u', 'U':
+		if !s.hasDigit(16) {
+			break
+		}
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		r := rune(s.scanDigits(16, n))
+		if r > unicode.MaxRune {
+			r = utf8.RuneError
+		}
+		var p [utf8.UTFMax]byte
+		return append(buf, p[:utf8.EncodeRune(p[:], r)]...)
+	case 'c':
+		if s.pos < len(s.str) {
+			x := s.str[s.pos]
+			s.pos++
+			return append(buf, x&0x1f)
+		}
+	}
+	return append(buf, '\\', c)
+}
+
+func (s *shellScanner) scanExpansion(buf []byte) ([]byte, error) {
+	start := s.pos
+	s.pos++
+	if s.pos < len(s.str) && s.str[s.pos] == '{' {
+		return s.scanBraceExpansion(buf, start)
+	}
+	name := s.scanName()
+	if name == "" {
+		return append(buf, '$'), nil
+	}
+	value, _ := s.lookup(name)
+	return append(buf, value...), nil
+}
+
+func (s *shellScanner) scanBraceExpansion(buf []byte, start int) ([]byte, error) {
+	s.pos++
+	name := s.scanName()
+	if name == "" || s.pos >= len(s.str) {
+		return nil, newParseErrorAt(ErrBadSubstitution, s.str, start)
+	}
+
+	value, ok := s.lookup(name)
+	if s.str[s.pos] == '}' {
+		s.pos++
+		return append(buf, value...), nil
+	}
+
+	colon := s.str[s.pos] == ':'
+	if colon {
+		s.pos++
+	}
+	if s.pos >= len(s.str) || (s.str[s.pos] != '-' && s.str[s.pos] != '?') {
+		return nil, newParseErrorAt(ErrBadSubstitution, s.str, start)
+	}
+	op := s.str[s.pos]
+	s.pos++
+
+	unset := !ok || (colon && value == "")
+	skip := s.skip
+	s.skip = skip || !unset
+	word, err := s.scanBraceWord(start)
+	s.skip = skip
+	if err != nil {
+		return nil, err
+	}
+	if !unset {
+		return append(buf, value...), nil
+	}
+
+	switch op {
+	case '-':
+		return append(buf, word...), nil
+	default:
+		if s.skip {
+			return buf, nil
+		}
+		return nil, newParseErrorAt(newUnresolvedVariableError(name, word), s.str, start)
+	}
+}
+
+func (s *shellScanner) scanBraceWord(start int) (string, error) {
+	buf := make([]byte, 0, 64)
+	for s.pos < len(s.str) {
+		c := s.str[s.pos]
+		var err error
+		switch {
+		case c == '}':
+			s.pos++
+			return string(buf), nil
+		case c == '$':
+			buf, err = s.scanExpansion(buf)
+		case c == '\\' && !s.plain:
+			buf, err = s.scanEscape(buf)
+		case c == '\'' && !s.plain:
+			buf, err = s.scanSingleQuoted(buf)
+		case c == '"' && !s.plain:
+			buf, err = s.scanDoubleQuoted(buf)
+		default:
+			buf = append(buf, c)
+			s.pos++
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", newParseErrorAt(ErrBadSubstitution, s.str, start)
+}
+
+// expandPlain performs parameter expansions in plain mode.
+func (s *shellScanner) expandPlain() (string, error) {
+	buf := make([]byte, 0, len(s.str))
+	for s.pos < len(s.str) {
+		if s.str[s.pos] != '$' {
+			buf = append(buf, s.str[s.pos])
+			s.pos++
+			continue
+		}
+		var err error
+		buf, err = s.scanExpansion(buf)
+		if err != nil {
+			return "", err
+		}
+	}
+	return string(buf), nil
+}
+
+func (s *shellScanner) scanName() string {
+	start := s.pos
+	for s.pos < len(s.str) {
+		c := s.str[s.pos]
+		if c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (s.pos > start && '0' <= c && c <= '9') {
+			s.pos++
+			continue
+		}
+		break
+	}
+	return s.str[start:s.pos]
+}
+
+func (s *shellScanner) hasDigit(base int) bool {
+	return s.pos < len(s.str) && digitValue(s.str[s.pos]) < base
+}
+
+func (s *shellScanner) scanDigits(base int, max int) int {
+	x := 0
+	for i := 0; i < max && s.hasDigit(base); i++ {
+		x = x*base + digitValue(s.str[s.pos])
+		s.pos++
+	}
+	return x
+}
+
+func (s *shellScanner) skipSpace() {
+	for s.pos < len(s.str) {
+		if strings.HasPrefix(s.str[s.pos:], "\\\n") {
+			s.pos += 2
+			continue
+		}
+		r, size := s.peek()
+		if !unicode.IsSpace(r) {
+			break
+		}
+		s.pos += size
+	}
+}
+
+func (s *shellScanner) skipLine() {
+	idx := strings.IndexByte(s.str[s.pos:], '\n')
+	if idx < 0 {
+		s.pos = len(s.str)
+		return
+	}
+	s.pos += idx + 1
+}
+
+func (s *shellScanner) peek() (rune, int) {
+	return utf8.DecodeRuneInString(s.str[s.pos:])
+}
+
+func digitValue(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return 16
+}
+
+func (a *Arguments) formatShell(args ...string) string {
+	buf := make([]byte, 0, 4096)
+	for idx, arg := range args {
+		if idx > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = a.appendShellQuoted(buf, arg)
+	}
+	return string(buf)
+}
+
+// appendShellQuoted appends the shortest quoting of arg which is parsed back as a single word.
+func (a *Arguments) appendShellQuoted(buf []byte, arg string) []byte {
+	if arg == "" {
+		return append(buf, "''"...)
+	}
+
+	safe, printable := true, true
+	for idx, r := range arg {
+		if r == utf8.RuneError && !strings.HasPrefix(arg[idx:], string(utf8.RuneError)) {
+			safe, printable = false, false
+			break
+		}
+		if !unicode.IsPrint(r) {
+			safe, printable = false, false
+			break
+		}
+		if !isShellSafeRune(r) || (idx == 0 && a.Comment != 0 && r == a.Comment) {
+			safe = false
+		}
+	}
+
+	switch {
+	case safe:
+		return append(buf, arg...)
+
+	case printable && !strings.ContainsRune(arg, '\''):
+		buf = append(buf, '\'')
+		buf = append(buf, arg...)
+		return append(buf, '\'')
+
+	case printable && !strings.ContainsRune(arg, '!'):
+		buf = append(buf, '"')
+		for i := 0; i < len(arg); i++ {
+			switch c := arg[i]; c {
+			case '$', '`', '"', '\\':
+				buf = append(buf, '\\', c)
+			default:
+				buf = append(buf, c)
+			}
+		}
+		return append(buf, '"')
+
+	case printable:
+		buf = append(buf, '\'')
+		buf = append(buf, strings.Replace(arg, "'", `'\''`, -1)...)
+		return append(buf, '\'')
+
+	}
+
+	buf = append(buf, "$'"...)
+	for i := 0; i < len(arg); {
+		r, size := utf8.DecodeRuneInString(arg[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			buf = appendHexEscape(buf, 'x', uint32(arg[i]), 2)
+		case r == '\\' || r == '\'':
+			buf = append(buf, '\\', byte(r))
+		case r == '\a':
+			buf = append(buf, `\a`...)
+		case r == '\b':
+			buf = append(buf, `\b`...)
+		case r == '\f':
+			buf = append(buf, `\f`...)
+		case r == '\n':
+			buf = append(buf, `\n`...)
+		case r == '\r':
+			buf = append(buf, `\r`...)
+		case r == '\t':
+			buf = append(buf, `\t`...)
+		case r == '\v':
+			buf = append(buf, `\v`...)
+		case unicode.IsPrint(r):
+			buf = append(buf, arg[i:i+size]...)
+		case r < utf8.RuneSelf:
+			buf = appendHexEscape(buf, 'x', uint32(r), 2)
+		case r <= 0xffff:
+			buf = appendHexEscape(buf, 'u', uint32(r), 4)
+		default:
+			buf = appendHexEscape(buf, 'U', uint32(r), 8)
+		}
+		i += size
+	}
+	return append(buf, '\'')
+}
+
+func isShellSafeRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return true
+	}
+	return strings.ContainsRune("-_./:,+@%=", r)
+}
+
+func appendHexEscape(buf []byte, prefix byte, x uint32, width int) []byte {
+	const hexDigits = "0123456789abcdef"
+	buf = append(buf, '\\', prefix)
+	for i := width - 1; i >= 0; i-- {
+		buf = append(buf, hexDigits[(x>>(4*uint(i)))&0xf])
+	}
+	return buf
+}
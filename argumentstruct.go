@@ -4,16 +4,45 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type ArgumentStruct struct {
 	Unmarshaler              *Unmarshaler
+	Marshaler                *Marshaler
 	FieldNameBeginsLowerCase bool
 	FieldNameFold            bool
 	FieldTagKey              string
 	FieldOffset              int
 	ArgCountMin              int
 	ArgCountMax              int
+
+	// OptionTagKey is the struct tag key which marks fields as named options instead of positional arguments.
+	// The tag value is "long,s": long name defaults to field name, and long name "-" means short name only.
+	// Boolean fields are flags, the other fields take a value. If OptionTagKey is empty, DefaultOptionTagKey is used.
+	OptionTagKey string
+
+	// DefaultTagKey is the struct tag key of the default value which is used when the argument is not supplied.
+	// Default values of arrays and slices are split into arguments by shell grammar.
+	// If DefaultTagKey is empty, DefaultDefaultTagKey is used.
+	DefaultTagKey string
+
+	// ValidateTagKey is the struct tag key of the comma separated validation constraints
+	// which are checked for all fields after the arguments and default values are set.
+	// If ValidateTagKey is empty, validation is disabled.
+	ValidateTagKey string
+
+	// HelpTagKey is the struct tag key of the help text of the field which is reported by Fields.
+	// The first line of the help text is the short help, and the rest is the long help.
+	// If HelpTagKey is empty, DefaultHelpTagKey is used.
+	HelpTagKey string
+
+	// CodecTagKey is the struct tag key of the codec options of the field, e.g. "base=16" or "sep=,",
+	// which override Unmarshaler and Marshaler for the field. See Unmarshaler.WithOptions.
+	// Arrays and slices with option sep are formatted into one argument, and each of their arguments is split by sep.
+	// If CodecTagKey is empty, DefaultCodecTagKey is used.
+	CodecTagKey string
 }
 
 func (a *ArgumentStruct) Unmarshal(ifc interface{}, args ...string) error {
@@ -21,14 +50,21 @@ func (a *ArgumentStruct) Unmarshal(ifc interface{}, args ...string) error {
 }
 
 func (a *ArgumentStruct) UnmarshalByValue(val reflect.Value, args ...string) error {
+	args, err := a.unmarshalOptions(val, args...)
+	if err != nil {
+		return err
+	}
+
 	sizeArgs := len(args)
 	if a.ArgCountMax > 0 && sizeArgs > a.ArgCountMax {
 		return ErrArgumentCountExceeded
 	}
 
-	var err error
 	argIdx := 0
-	e := a.fieldsFunc(val, false, func(fieldName string, fieldVal reflect.Value) bool {
+	e := a.fieldsFunc(val, false, func(fieldName string, sf reflect.StructField, fieldVal reflect.Value) bool {
+		if a.getOption(sf, fieldName) != nil {
+			return false
+		}
 		fieldMinArgCount := getArgumentStructFieldMinArgCount(fieldVal.Type())
 		if lastArgIdx := argIdx + fieldMinArgCount; lastArgIdx > sizeArgs {
 			if argIdx < sizeArgs {
@@ -43,14 +79,16 @@ func (a *ArgumentStruct) UnmarshalByValue(val reflect.Value, args ...string) err
 				return true
 			}
 
-			fieldVal.Set(reflect.Zero(fieldVal.Type()))
+			if err = a.setFieldDefault(fieldVal, fieldName, sf); err != nil {
+				return true
+			}
 
 			argIdx += fieldMinArgCount
 			return false
 		}
 
 		var count int
-		count, err = a.setFieldVal(fieldVal, fieldName, args[argIdx:]...)
+		count, err = a.setFieldVal(fieldVal, fieldName, sf, args[argIdx:]...)
 		if err != nil {
 			return true
 		}
@@ -60,7 +98,172 @@ func (a *ArgumentStruct) UnmarshalByValue(val reflect.Value, args ...string) err
 	if e != nil {
 		return e
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	return a.validateFields(val)
+}
+
+// unmarshalOptions sets option fields from args, and returns the remaining positional arguments.
+func (a *ArgumentStruct) unmarshalOptions(val reflect.Value, args ...string) ([]string, error) {
+	type optionField struct {
+		*argumentStructOption
+		name   string
+		sf     reflect.StructField
+		val    reflect.Value
+		values []string
+	}
+
+	fields := make([]*optionField, 0, 16)
+	err := a.fieldsFunc(val, false, func(fieldName string, sf reflect.StructField, fieldVal reflect.Value) bool {
+		if opt := a.getOption(sf, fieldName); opt != nil {
+			fields = append(fields, &optionField{
+				argumentStructOption: opt,
+				name:                 fieldName,
+				sf:                   sf,
+				val:                  fieldVal,
+			})
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) <= 0 {
+		return args, nil
+	}
+
+	opts := make([]*argumentStructOption, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		opts = append(opts, field.argumentStructOption)
+		names = append(names, field.name)
+	}
+	result, err := splitOptions(opts, names, a.FieldNameFold, args, func(idx int, value string) {
+		fields[idx].values = append(fields[idx].values, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		values := field.values
+		if len(values) <= 0 {
+			if err := a.setFieldDefault(field.val, field.name, field.sf); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if k := field.val.Type().Kind(); len(values) > 0 && k != reflect.Slice && k != reflect.Array &&
+			!(k == reflect.Ptr && (field.val.Type().Elem().Kind() == reflect.Slice || field.val.Type().Elem().Kind() == reflect.Array)) {
+			values = values[len(values)-1:]
+		}
+		if _, err := a.setFieldVal(field.val, field.name, field.sf, values...); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Marshal is the inverse of Unmarshal. It formats the fields of ifc into arguments.
+// Arrays and slices expand to multiple arguments, and trailing optional arguments and options
+// which would be set to the same value by Unmarshal are omitted. If a value can't be expressed,
+// e.g. false of a boolean option which has only short name and defaults to true, FormatError is returned.
+func (a *ArgumentStruct) Marshal(ifc interface{}) ([]string, error) {
+	return a.MarshalByValue(reflect.ValueOf(ifc))
+}
+
+func (a *ArgumentStruct) MarshalByValue(val reflect.Value) ([]string, error) {
+	type positional struct {
+		values    []string
+		omittable bool
+	}
+
+	positionals := make([]positional, 0, 16)
+	options := make([]string, 0, 16)
+	hasOptions := false
+	argIdx := 0
+	var err error
+	e := a.fieldsFunc(val, true, func(fieldName string, sf reflect.StructField, fieldVal reflect.Value) bool {
+		var marshaler *Marshaler
+		marshaler, err = a.getMarshaler(sf)
+		if err != nil {
+			err = newFormatError(err)
+			return true
+		}
+		var values []string
+		values, err = a.getFieldValues(marshaler, fieldVal)
+		if err != nil {
+			return true
+		}
+		omittable := a.isFieldDefault(fieldVal, fieldName, sf)
+
+		if opt := a.getOption(sf, fieldName); opt != nil {
+			hasOptions = true
+			if omittable || len(values) <= 0 {
+				return false
+			}
+			name := "--" + opt.long
+			if opt.long == "" {
+				name = "-" + string(opt.short)
+			}
+			if opt.boolean {
+				switch {
+				case values[0] == "true":
+					options = append(options, name)
+				case opt.long != "":
+					options = append(options, name+"="+values[0])
+				default:
+					err = newFormatError(fmt.Errorf("option %s=%s: %w", name, values[0], ErrCanNotExpressOptionValue))
+					return true
+				}
+				return false
+			}
+			for _, value := range values {
+				options = append(options, name, value)
+			}
+			return false
+		}
+
+		if a.ArgCountMax > 0 && a.ArgCountMax <= argIdx {
+			return false
+		}
+		positionals = append(positionals, positional{
+			values:    values,
+			omittable: argIdx >= a.ArgCountMin && omittable,
+		})
+		argIdx += getArgumentStructFieldMinArgCount(fieldVal.Type())
+		return false
+	})
+	if e != nil {
+		return nil, e
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for l := len(positionals); l > 0 && positionals[l-1].omittable; l-- {
+		positionals = positionals[:l-1]
+	}
+
+	result := make([]string, 0, len(positionals)+len(options)+1)
+	optionsDone := false
+	for _, p := range positionals {
+		for _, value := range p.values {
+			if !optionsDone && hasOptions && len(value) > 1 && value[0] == '-' {
+				result = append(result, options...)
+				result = append(result, "--")
+				optionsDone = true
+			}
+			result = append(result, value)
+		}
+	}
+	if !optionsDone {
+		result = append(result, options...)
+	}
+
+	return result, nil
 }
 
 func (a *ArgumentStruct) Fields(ifc interface{}) (ArgumentStructFields, error) {
@@ -70,10 +273,7 @@ func (a *ArgumentStruct) Fields(ifc interface{}) (ArgumentStructFields, error) {
 func (a *ArgumentStruct) FieldsByValue(val reflect.Value) (ArgumentStructFields, error) {
 	result := make(ArgumentStructFields, 0, 1024)
 	argIdx := 0
-	err := a.fieldsFunc(val, true, func(fieldName string, fieldVal reflect.Value) bool {
-		if a.ArgCountMax > 0 && a.ArgCountMax <= argIdx {
-			return true
-		}
+	err := a.fieldsFunc(val, true, func(fieldName string, sf reflect.StructField, fieldVal reflect.Value) bool {
 		typ := fieldVal.Type()
 		typ2 := typ
 		isPtr := typ2.Kind() == reflect.Ptr
@@ -81,11 +281,37 @@ func (a *ArgumentStruct) FieldsByValue(val reflect.Value) (ArgumentStructFields,
 			typ2 = typ2.Elem()
 		}
 		fieldMinArgCount := getArgumentStructFieldMinArgCount(typ2)
+		defaultValue, _ := a.getDefault(sf)
+		help, longHelp := a.getHelp(sf)
+		if opt := a.getOption(sf, fieldName); opt != nil {
+			result = append(result, ArgumentStructField{
+				Name:        fieldName,
+				Optional:    true,
+				MinArgCount: fieldMinArgCount,
+				Variadic:    typ2.Kind() == reflect.Slice,
+				Default:     defaultValue,
+				Type:        typ2,
+				Help:        help,
+				LongHelp:    longHelp,
+				Option:      true,
+				Long:        opt.long,
+				Short:       opt.short,
+				Boolean:     opt.boolean,
+			})
+			return false
+		}
+		if a.ArgCountMax > 0 && a.ArgCountMax <= argIdx {
+			return false
+		}
 		result = append(result, ArgumentStructField{
 			Name:        fieldName,
 			Optional:    argIdx >= a.ArgCountMin,
 			MinArgCount: fieldMinArgCount,
 			Variadic:    typ2.Kind() == reflect.Slice,
+			Default:     defaultValue,
+			Type:        typ2,
+			Help:        help,
+			LongHelp:    longHelp,
 		})
 		argIdx += fieldMinArgCount
 		return false
@@ -105,7 +331,7 @@ func (a *ArgumentStruct) GetField(ifc interface{}, name string) (interface{}, st
 }
 
 func (a *ArgumentStruct) GetFieldByValue(val reflect.Value, name string) (reflect.Value, string, error) {
-	fieldVal, name, err := a.find(val, true, name)
+	fieldVal, _, name, err := a.find(val, true, name)
 	if err != nil {
 		return reflect.Value{}, name, err
 	}
@@ -124,12 +350,12 @@ func (a *ArgumentStruct) SetField(ifc interface{}, name string, values ...string
 }
 
 func (a *ArgumentStruct) SetFieldByValue(val reflect.Value, name string, values ...string) (reflect.Value, string, error) {
-	fieldVal, name, err := a.find(val, false, name)
+	fieldVal, sf, name, err := a.find(val, false, name)
 	if err != nil {
 		return reflect.Value{}, name, err
 	}
 
-	_, err = a.setFieldVal(fieldVal, name, values...)
+	_, err = a.setFieldVal(fieldVal, name, sf, values...)
 	if err != nil {
 		return reflect.Value{}, name, err
 	}
@@ -139,10 +365,10 @@ func (a *ArgumentStruct) SetFieldByValue(val reflect.Value, name string, values
 	return result, name, nil
 }
 
-func (a *ArgumentStruct) setFieldVal(val reflect.Value, name string, values ...string) (count int, err error) {
-	unmarshaler := a.Unmarshaler
-	if unmarshaler == nil {
-		unmarshaler = NewUnmarshaler()
+func (a *ArgumentStruct) setFieldVal(val reflect.Value, name string, sf reflect.StructField, values ...string) (count int, err error) {
+	unmarshaler, err := a.getUnmarshaler(sf)
+	if err != nil {
+		return 0, &ArgumentParseError{name, err}
 	}
 
 	typ := val.Type()
@@ -164,12 +390,26 @@ func (a *ArgumentStruct) setFieldVal(val reflect.Value, name string, values ...s
 	case reflect.Array:
 		fallthrough
 	case reflect.Slice:
-		av = reflect.New(reflect.ArrayOf(sizeValues, typ2.Elem())).Elem()
+		elems := make([]reflect.Value, 0, sizeValues)
 		for i := 0; i < sizeValues; i++ {
+			if unmarshaler.CollectionSep != "" {
+				v, err := unmarshaler.ParseToValue(values[i], reflect.SliceOf(typ2.Elem()))
+				if err != nil {
+					return 0, &ArgumentParseError{name, err.(*ParseError).Unwrap()}
+				}
+				for j, k := 0, v.Len(); j < k; j++ {
+					elems = append(elems, v.Index(j))
+				}
+				continue
+			}
 			v, err := unmarshaler.ParseToValue(values[i], typ2.Elem())
 			if err != nil {
 				return 0, &ArgumentParseError{name, err.(*ParseError).Unwrap()}
 			}
+			elems = append(elems, v)
+		}
+		av = reflect.New(reflect.ArrayOf(len(elems), typ2.Elem())).Elem()
+		for i, v := range elems {
 			av.Index(i).Set(v)
 		}
 	default:
@@ -183,7 +423,7 @@ func (a *ArgumentStruct) setFieldVal(val reflect.Value, name string, values ...s
 			count = sizeValues
 		}
 		if isPtr {
-			if sizeValues != typ2.Len() {
+			if av.Len() != typ2.Len() {
 				val.Set(reflect.New(reflect.ArrayOf(typ2.Len(), typ2.Elem())))
 				reflect.Copy(val.Elem(), av)
 				break
@@ -216,43 +456,156 @@ func (a *ArgumentStruct) setFieldVal(val reflect.Value, name string, values ...s
 	return count, nil
 }
 
-func (a *ArgumentStruct) find(val reflect.Value, readOnly bool, name string) (reflect.Value, string, error) {
-	var result reflect.Value
+// getFieldValues formats val into arguments.
+func (a *ArgumentStruct) getFieldValues(marshaler *Marshaler, val reflect.Value) ([]string, error) {
+	typ := val.Type()
+	if typ.Kind() == reflect.Ptr && (typ.Elem().Kind() == reflect.Array || typ.Elem().Kind() == reflect.Slice) {
+		if val.IsNil() {
+			return []string{}, nil
+		}
+		val = val.Elem()
+		typ = typ.Elem()
+	}
 
-	err := a.fieldsFunc(val, readOnly, func(fieldName string, fieldVal reflect.Value) bool {
-		var ok bool
-		if a.FieldNameFold {
-			ok = strings.EqualFold(fieldName, name)
-		} else {
-			ok = fieldName == name
+	switch typ.Kind() {
+	case reflect.Array:
+		fallthrough
+	case reflect.Slice:
+		if marshaler.CollectionSep != "" {
+			str, err := marshaler.MarshalByValue(val)
+			if err != nil {
+				return nil, err
+			}
+			return []string{str}, nil
+		}
+		result := make([]string, 0, val.Len())
+		for i, j := 0, val.Len(); i < j; i++ {
+			str, err := marshaler.MarshalByValue(val.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, str)
+		}
+		return result, nil
+
+	default:
+		str, err := marshaler.MarshalByValue(val)
+		if err != nil {
+			return nil, err
+		}
+		return []string{str}, nil
+
+	}
+}
+
+// isFieldDefault reports whether val equals the value which is set when the argument is not supplied.
+func (a *ArgumentStruct) isFieldDefault(val reflect.Value, name string, sf reflect.StructField) bool {
+	defaultVal := reflect.New(val.Type()).Elem()
+	if err := a.setFieldDefault(defaultVal, name, sf); err != nil {
+		return false
+	}
+	if val.Kind() == reflect.Slice && val.Len() <= 0 && defaultVal.Len() <= 0 {
+		return true
+	}
+	return reflect.DeepEqual(val.Interface(), defaultVal.Interface())
+}
+
+// setFieldDefault sets val to the default value in the struct tag, or to zero if there is no default value.
+func (a *ArgumentStruct) setFieldDefault(val reflect.Value, name string, sf reflect.StructField) error {
+	defaultValue, ok := a.getDefault(sf)
+	if !ok {
+		val.Set(reflect.Zero(val.Type()))
+		return nil
+	}
+
+	values := []string{defaultValue}
+	typ := val.Type()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Array || typ.Kind() == reflect.Slice {
+		var err error
+		values, err = (&Arguments{Shell: true}).Parse(defaultValue)
+		if err != nil {
+			return &ArgumentParseError{name, err}
 		}
-		if ok {
+	}
+
+	_, err := a.setFieldVal(val, name, sf, values...)
+	return err
+}
+
+func (a *ArgumentStruct) getDefault(sf reflect.StructField) (string, bool) {
+	defaultTagKey := a.DefaultTagKey
+	if defaultTagKey == "" {
+		defaultTagKey = DefaultDefaultTagKey
+	}
+	return sf.Tag.Lookup(defaultTagKey)
+}
+
+// getUnmarshaler returns Unmarshaler which is overridden by the codec options of the field.
+func (a *ArgumentStruct) getUnmarshaler(sf reflect.StructField) (*Unmarshaler, error) {
+	return getFieldUnmarshaler(a.Unmarshaler, a.CodecTagKey, sf)
+}
+
+// getMarshaler returns Marshaler which is overridden by the codec options of the field.
+func (a *ArgumentStruct) getMarshaler(sf reflect.StructField) (*Marshaler, error) {
+	return getFieldMarshaler(a.Marshaler, a.CodecTagKey, sf)
+}
+
+func (a *ArgumentStruct) getHelp(sf reflect.StructField) (string, string) {
+	helpTagKey := a.HelpTagKey
+	if helpTagKey == "" {
+		helpTagKey = DefaultHelpTagKey
+	}
+	help := strings.TrimSpace(sf.Tag.Get(helpTagKey))
+	if idx := strings.IndexByte(help, '\n'); idx >= 0 {
+		return strings.TrimSpace(help[:idx]), strings.TrimSpace(help[idx+1:])
+	}
+	return help, ""
+}
+
+func (a *ArgumentStruct) find(val reflect.Value, readOnly bool, name string) (reflect.Value, reflect.StructField, string, error) {
+	var result reflect.Value
+	var resultSf reflect.StructField
+
+	err := a.fieldsFunc(val, readOnly, func(fieldName string, sf reflect.StructField, fieldVal reflect.Value) bool {
+		if equalFieldName(fieldName, name, a.FieldNameFold) {
 			name = fieldName
 			result = fieldVal
+			resultSf = sf
 			return true
 		}
 		return false
 	})
 	if err != nil {
-		return reflect.Value{}, name, err
+		return reflect.Value{}, reflect.StructField{}, name, err
 	}
 
 	if result.IsValid() {
-		return result, name, nil
+		return result, resultSf, name, nil
 	}
 
-	return reflect.Value{}, name, ErrArgumentStructFieldNotFound
+	return reflect.Value{}, reflect.StructField{}, name, ErrArgumentStructFieldNotFound
 }
 
-func (a *ArgumentStruct) fieldsFunc(val reflect.Value, readOnly bool, f func(fieldName string, fieldVal reflect.Value) bool) error {
+// fieldsFunc calls f for each field of the struct which val points to, until f returns true.
+// Positional fields after a variadic positional field are skipped, but option fields are still visited.
+func (a *ArgumentStruct) fieldsFunc(val reflect.Value, readOnly bool, f func(fieldName string, sf reflect.StructField, fieldVal reflect.Value) bool) error {
+	variadic := false
+	_, err := a.fieldsFuncRecursive(val, readOnly, &variadic, f)
+	return err
+}
+
+func (a *ArgumentStruct) fieldsFuncRecursive(val reflect.Value, readOnly bool, variadic *bool, f func(fieldName string, sf reflect.StructField, fieldVal reflect.Value) bool) (bool, error) {
 	if val.Type().Kind() != reflect.Ptr {
 		if !val.CanAddr() {
-			return ErrCanNotGetAddr
+			return false, ErrCanNotGetAddr
 		}
 		val = val.Addr()
 	}
 	if val.IsNil() {
-		return ErrNilPointer
+		return false, ErrNilPointer
 	}
 
 	v := val
@@ -260,7 +613,7 @@ func (a *ArgumentStruct) fieldsFunc(val reflect.Value, readOnly bool, f func(fie
 	typ := val.Type()
 
 	if typ.Kind() != reflect.Struct {
-		return ErrValueMustBeStruct
+		return false, ErrValueMustBeStruct
 	}
 
 	offset := a.FieldOffset
@@ -286,40 +639,176 @@ func (a *ArgumentStruct) fieldsFunc(val reflect.Value, readOnly bool, f func(fie
 			case sf.Type.Kind() == reflect.Interface:
 				curFieldVal = fieldVal.Elem()
 			}
-			if err := a.fieldsFunc(curFieldVal, readOnly, f); err != nil {
-				return err
+			stop, err := a.fieldsFuncRecursive(curFieldVal, readOnly, variadic, f)
+			if err != nil {
+				return false, err
 			}
 			if isNilPtr && !readOnly {
 				fieldVal.Set(curFieldVal)
 			}
+			if stop {
+				return true, nil
+			}
 			continue
 		}
-		fieldName := sf.Name
-		if a.FieldNameBeginsLowerCase {
-			fieldName = ToLowerBeginning(fieldName)
+		fieldName, ok := getFieldName(sf, a.FieldNameBeginsLowerCase, a.FieldTagKey)
+		if !ok {
+			continue
 		}
-		if a.FieldTagKey != "" {
-			fieldTagFieldName := sf.Tag.Get(a.FieldTagKey)
-			if idx := strings.Index(fieldTagFieldName, ","); idx >= 0 {
-				fieldTagFieldName = fieldTagFieldName[:idx]
-			}
-			if fieldTagFieldName == "-" {
+
+		isOption := a.getOption(sf, fieldName) != nil
+		if *variadic && !isOption {
+			continue
+		}
+
+		if f(fieldName, sf, fieldVal) {
+			return true, nil
+		}
+
+		if typ := fieldVal.Type(); !isOption && (typ.Kind() == reflect.Slice || (typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Slice)) {
+			*variadic = true
+		}
+	}
+	return false, nil
+}
+
+func (a *ArgumentStruct) getOption(sf reflect.StructField, fieldName string) *argumentStructOption {
+	optionTagKey := a.OptionTagKey
+	if optionTagKey == "" {
+		optionTagKey = DefaultOptionTagKey
+	}
+
+	tag, ok := sf.Tag.Lookup(optionTagKey)
+	if !ok {
+		return nil
+	}
+
+	opt := &argumentStructOption{
+		long: tag,
+	}
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		opt.long = tag[:idx]
+		opt.short, _ = utf8.DecodeRuneInString(tag[idx+1:])
+		if opt.short == utf8.RuneError {
+			opt.short = 0
+		}
+	}
+	switch opt.long {
+	case "":
+		opt.long = fieldName
+	case "-":
+		opt.long = ""
+		if opt.short == 0 {
+			return nil
+		}
+	}
+
+	typ := sf.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	opt.boolean = typ.Kind() == reflect.Bool
+
+	return opt
+}
+
+type argumentStructOption struct {
+	long    string
+	short   rune
+	boolean bool
+}
+
+// splitOptions splits args into the options and the positional arguments. opts are the options, and names are
+// the field names of them. f is called with the index of each option in opts and its value, in order of args.
+// Long options are matched case-insensitively if fold is true. Boolean options take the value "true".
+func splitOptions(opts []*argumentStructOption, names []string, fold bool, args []string, f func(idx int, value string)) ([]string, error) {
+	findLong := func(name string) int {
+		for idx, opt := range opts {
+			if opt.long == "" {
 				continue
 			}
-			if fieldTagFieldName != "" {
-				fieldName = fieldTagFieldName
+			if opt.long == name || (fold && strings.EqualFold(opt.long, name)) {
+				return idx
+			}
+		}
+		return -1
+	}
+	findShort := func(r rune) int {
+		for idx, opt := range opts {
+			if opt.short != 0 && opt.short == r {
+				return idx
 			}
 		}
+		return -1
+	}
+
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
 
-		if f(fieldName, fieldVal) {
+		if arg == "--" {
+			result = append(result, args[i+1:]...)
 			break
 		}
 
-		if typ := fieldVal.Type(); typ.Kind() == reflect.Slice || (typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Slice) {
+		if len(arg) < 2 || arg[0] != '-' {
+			result = append(result, arg)
+			continue
+		}
+
+		if strings.HasPrefix(arg, "--") {
+			name, value := arg[2:], ""
+			idx := strings.Index(name, "=")
+			if idx >= 0 {
+				name, value = name[:idx], name[idx+1:]
+			}
+			optIdx := findLong(name)
+			if optIdx < 0 {
+				return nil, &UnknownOptionError{"--" + name, nil}
+			}
+			switch {
+			case idx >= 0:
+			case opts[optIdx].boolean:
+				value = "true"
+			case i+1 < len(args):
+				i++
+				value = args[i]
+			default:
+				return nil, &MissingArgumentError{names[optIdx], nil}
+			}
+			f(optIdx, value)
+			continue
+		}
+
+		if r, _ := utf8.DecodeRuneInString(arg[1:]); (unicode.IsDigit(r) || r == '.') && findShort(r) < 0 {
+			result = append(result, arg)
+			continue
+		}
+
+		for j := 1; j < len(arg); {
+			r, size := utf8.DecodeRuneInString(arg[j:])
+			j += size
+			optIdx := findShort(r)
+			if optIdx < 0 {
+				return nil, &UnknownOptionError{"-" + string(r), nil}
+			}
+			if opts[optIdx].boolean {
+				f(optIdx, "true")
+				continue
+			}
+			value := arg[j:]
+			if value == "" {
+				if i+1 >= len(args) {
+					return nil, &MissingArgumentError{names[optIdx], nil}
+				}
+				i++
+				value = args[i]
+			}
+			f(optIdx, value)
 			break
 		}
 	}
-	return nil
+	return result, nil
 }
 
 func getArgumentStructFieldMinArgCount(typ reflect.Type) int {
@@ -343,15 +832,82 @@ type ArgumentStructField struct {
 	Optional    bool
 	MinArgCount int
 	Variadic    bool
+	Default     string
+
+	// Type is the type of the field, or the type pointed by the field if it is a pointer.
+	Type reflect.Type
+
+	// Help and LongHelp are the short and long help text of the field.
+	Help     string
+	LongHelp string
+
+	// Option reports whether the field is a named option. Long and Short are the option names,
+	// and Boolean reports whether the option is a flag which takes no value.
+	Option  bool
+	Long    string
+	Short   rune
+	Boolean bool
 }
 
 type ArgumentStructFields []ArgumentStructField
 
+// SplitOptions splits args into the options and the positional arguments by the option fields of a,
+// like ArgumentStruct.Unmarshal. f is called with each option field and its value, in order of args.
+// Long options are matched case-insensitively if fold is true, as ArgumentStruct.FieldNameFold.
+// UnknownOptionError is returned for an unknown option, and MissingArgumentError for an option
+// which misses its value at the end of args.
+func (a ArgumentStructFields) SplitOptions(fold bool, f func(field *ArgumentStructField, value string), args ...string) ([]string, error) {
+	opts := make([]*argumentStructOption, 0, len(a))
+	names := make([]string, 0, len(a))
+	fields := make([]*ArgumentStructField, 0, len(a))
+	for idx := range a {
+		field := &a[idx]
+		if !field.Option {
+			continue
+		}
+		opts = append(opts, &argumentStructOption{field.Long, field.Short, field.Boolean})
+		names = append(names, field.Name)
+		fields = append(fields, field)
+	}
+	if len(opts) <= 0 {
+		return args, nil
+	}
+	return splitOptions(opts, names, fold, args, func(idx int, value string) {
+		if f != nil {
+			f(fields[idx], value)
+		}
+	})
+}
+
 func (a ArgumentStructFields) String() string {
 	result := ""
-	idx := 0
+
+	options := make(ArgumentStructFields, 0, len(a))
+	positionals := make(ArgumentStructFields, 0, len(a))
+	for _, field := range a {
+		if field.Option {
+			options = append(options, field)
+			continue
+		}
+		positionals = append(positionals, field)
+	}
 
 	str := ""
+	for _, field := range options {
+		if str != "" {
+			str += " "
+		}
+		str += "[" + field.OptionString() + "]"
+		if field.Variadic {
+			str += "..."
+		}
+	}
+	result += str
+
+	a = positionals
+	idx := 0
+
+	str = ""
 	for _, field := range a[idx:] {
 		if field.Optional {
 			break
@@ -365,7 +921,7 @@ func (a ArgumentStructFields) String() string {
 			vari = "..."
 		}
 		if field.MinArgCount <= 1 {
-			str += fmt.Sprintf("<%s>%s", field.Name, vari)
+			str += fmt.Sprintf("<%s>%s", field.nameWithDefault(), vari)
 		} else {
 			for i := 0; i < field.MinArgCount; i++ {
 				if i > 0 {
@@ -375,6 +931,9 @@ func (a ArgumentStructFields) String() string {
 			}
 		}
 	}
+	if result != "" && str != "" {
+		result += " "
+	}
 	result += str
 
 	str = ""
@@ -389,7 +948,7 @@ func (a ArgumentStructFields) String() string {
 		}
 		str += "["
 		if field.MinArgCount <= 1 {
-			str += fmt.Sprintf("<%s>%s", field.Name, vari)
+			str += fmt.Sprintf("<%s>%s", field.nameWithDefault(), vari)
 		} else {
 			for i := 0; i < field.MinArgCount; i++ {
 				if i > 0 {
@@ -403,10 +962,67 @@ func (a ArgumentStructFields) String() string {
 	for i := 0; i < k; i++ {
 		str += "]"
 	}
-	if result != "" {
+	if result != "" && str != "" {
 		result += " "
 	}
 	result += str
 
 	return result
 }
+
+// OptionString returns the option names of the field like "-p|--port <port>".
+func (a ArgumentStructField) OptionString() string {
+	if !a.Option {
+		return ""
+	}
+	str := ""
+	if a.Short != 0 {
+		str += "-" + string(a.Short)
+	}
+	if a.Long != "" {
+		if str != "" {
+			str += "|"
+		}
+		str += "--" + a.Long
+	}
+	if !a.Boolean {
+		str += fmt.Sprintf(" <%s>", a.nameWithDefault())
+	}
+	return str
+}
+
+func (a ArgumentStructField) nameWithDefault() string {
+	if a.Default == "" {
+		return a.Name
+	}
+	return a.Name + "=" + a.Default
+}
+
+// getFieldName returns the name of the field sf, which is overridden by the tag fieldTagKey if it is not empty.
+// It returns false if the field is omitted by "-".
+func getFieldName(sf reflect.StructField, beginsLowerCase bool, fieldTagKey string) (string, bool) {
+	fieldName := sf.Name
+	if beginsLowerCase {
+		fieldName = ToLowerBeginning(fieldName)
+	}
+	if fieldTagKey != "" {
+		fieldTagFieldName := sf.Tag.Get(fieldTagKey)
+		if idx := strings.Index(fieldTagFieldName, ","); idx >= 0 {
+			fieldTagFieldName = fieldTagFieldName[:idx]
+		}
+		if fieldTagFieldName == "-" {
+			return "", false
+		}
+		if fieldTagFieldName != "" {
+			fieldName = fieldTagFieldName
+		}
+	}
+	return fieldName, true
+}
+
+func equalFieldName(x, y string, fold bool) bool {
+	if fold {
+		return strings.EqualFold(x, y)
+	}
+	return x == y
+}
@@ -0,0 +1,207 @@
+package xstrings
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestArgumentStructMarshalRoundTrip(t *testing.T) {
+	type args struct {
+		Verbose bool     `option:"verbose,v"`
+		Color   bool     `option:"color" default:"true"`
+		Level   int      `option:"level,l" default:"3"`
+		Tags    []string `option:"tag,t"`
+		Name    string
+		Count   int `default:"1"`
+		Files   []string
+	}
+	a := &ArgumentStruct{}
+	arguments := &Arguments{Shell: true}
+	cases := []args{
+		{},
+		{Color: true, Level: 3, Count: 1},
+		{Verbose: true, Level: 5, Tags: []string{"a", "b c"}, Name: "x", Count: 2, Files: []string{"f1", "f2"}},
+		{Color: false, Level: 3, Name: "-x", Count: 1},
+		{Level: 3, Name: "x", Count: -1, Files: []string{"--", "-"}},
+		{Tags: []string{"-t"}, Level: 3, Count: 1, Files: []string{""}},
+	}
+	for _, want := range cases {
+		argv, err := a.Marshal(&want)
+		if err != nil {
+			t.Errorf("Marshal(%+v) error: %v", want, err)
+			continue
+		}
+		str, err := arguments.Format(argv...)
+		if err != nil {
+			t.Errorf("Format(%q) error: %v", argv, err)
+			continue
+		}
+		argv2, err := arguments.Parse(str)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", str, err)
+			continue
+		}
+		var got args
+		if err := a.Unmarshal(&got, argv2...); err != nil {
+			t.Errorf("Unmarshal(%q) error: %v", argv2, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip of %+v by %q = %+v", want, str, got)
+		}
+	}
+}
+
+func TestArgumentStructMarshalError(t *testing.T) {
+	type args struct {
+		Force bool `option:"-,f" default:"true"`
+	}
+	a := &ArgumentStruct{}
+	if _, err := a.Marshal(&args{Force: false}); !errors.Is(err, ErrCanNotExpressOptionValue) {
+		t.Errorf("Marshal error = %v, want %v", err, ErrCanNotExpressOptionValue)
+	}
+	if argv, err := a.Marshal(&args{Force: true}); err != nil || len(argv) != 0 {
+		t.Errorf("Marshal = %q, %v, want no arguments", argv, err)
+	}
+}
+
+func TestArgumentStructOptions(t *testing.T) {
+	type args struct {
+		Verbose bool     `option:"verbose,v"`
+		Level   int      `option:"level,l"`
+		Tags    []string `option:"tag,t"`
+		Name    string
+		Rest    []string
+	}
+	a := &ArgumentStruct{}
+	cases := []struct {
+		in   []string
+		want args
+	}{
+		{[]string{"x"}, args{Name: "x"}},
+		{[]string{"--verbose", "--level=2", "x"}, args{Verbose: true, Level: 2, Name: "x"}},
+		{[]string{"--level", "2", "x", "--verbose=false"}, args{Level: 2, Name: "x"}},
+		{[]string{"-vl2", "x"}, args{Verbose: true, Level: 2, Name: "x"}},
+		{[]string{"-l", "-2", "-1", "-.5"}, args{Level: -2, Name: "-1", Rest: []string{"-.5"}}},
+		{[]string{"-t", "a", "--tag=b", "-tc", "x"}, args{Tags: []string{"a", "b", "c"}, Name: "x"}},
+		{[]string{"-l1", "-l2", "x"}, args{Level: 2, Name: "x"}},
+		{[]string{"x", "--", "-v", "--level=1"}, args{Name: "x", Rest: []string{"-v", "--level=1"}}},
+		{[]string{"-", "-v"}, args{Verbose: true, Name: "-"}},
+	}
+	for _, c := range cases {
+		var got args
+		if err := a.Unmarshal(&got, c.in...); err != nil {
+			t.Errorf("Unmarshal(%q) error: %v", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Unmarshal(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+
+	errCases := []struct {
+		in   []string
+		want interface{}
+	}{
+		{[]string{"--unknown", "x"}, new(*UnknownOptionError)},
+		{[]string{"-vx", "x"}, new(*UnknownOptionError)},
+		{[]string{"x", "--level"}, new(*MissingArgumentError)},
+		{[]string{"x", "-l"}, new(*MissingArgumentError)},
+		{[]string{"--level=a", "x"}, new(*ArgumentParseError)},
+	}
+	for _, c := range errCases {
+		var got args
+		if err := a.Unmarshal(&got, c.in...); !errors.As(err, c.want) {
+			t.Errorf("Unmarshal(%q) error = %v, want %T", c.in, err, reflect.ValueOf(c.want).Elem().Interface())
+		}
+	}
+}
+
+func TestArgumentStructDefaults(t *testing.T) {
+	type args struct {
+		Level int `option:"level,l" default:"3"`
+		Host  string
+		Port  int      `default:"8080"`
+		Tags  []string `default:"a 'b c'"`
+	}
+	a := &ArgumentStruct{ArgCountMin: 1}
+	cases := []struct {
+		in   []string
+		want args
+	}{
+		{[]string{"x"}, args{Level: 3, Host: "x", Port: 8080, Tags: []string{"a", "b c"}}},
+		{[]string{"-l1", "x", "80", "d"}, args{Level: 1, Host: "x", Port: 80, Tags: []string{"d"}}},
+	}
+	for _, c := range cases {
+		var got args
+		if err := a.Unmarshal(&got, c.in...); err != nil {
+			t.Errorf("Unmarshal(%q) error: %v", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Unmarshal(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+
+	fields, err := a.Fields(&args{})
+	if err != nil {
+		t.Fatalf("Fields error: %v", err)
+	}
+	if want := "3"; fields[0].Default != want {
+		t.Errorf("Fields[0].Default = %q, want %q", fields[0].Default, want)
+	}
+	if want := "[-l|--level <Level=3>] <Host> [<Port=8080> [<Tags=a 'b c'>...]]"; fields.String() != want {
+		t.Errorf("Fields.String = %q, want %q", fields.String(), want)
+	}
+
+	type badArgs struct {
+		Port int `default:"x"`
+	}
+	var e *ArgumentParseError
+	if err := (&ArgumentStruct{}).Unmarshal(&badArgs{}); !errors.As(err, &e) {
+		t.Errorf("Unmarshal with bad default error = %v, want *ArgumentParseError", err)
+	}
+}
+
+func TestArgumentStructFieldsSplitOptions(t *testing.T) {
+	type args struct {
+		Role  string `option:"role,r"`
+		Force bool   `option:"force,f"`
+		Name  string
+	}
+	fields, err := (&ArgumentStruct{}).Fields(&args{})
+	if err != nil {
+		t.Fatalf("Fields error: %v", err)
+	}
+	cases := []struct {
+		fold        bool
+		args        []string
+		options     []string
+		positionals []string
+		err         interface{}
+	}{
+		{false, []string{"-fr", "admin", "x"}, []string{"Force=true", "Role=admin"}, []string{"x"}, nil},
+		{false, []string{"-radmin", "-5", "--", "-f"}, []string{"Role=admin"}, []string{"-5", "-f"}, nil},
+		{false, []string{"--role=a", "--force", "-"}, []string{"Role=a", "Force=true"}, []string{"-"}, nil},
+		{true, []string{"--ROLE", "a"}, []string{"Role=a"}, []string{}, nil},
+		{false, []string{"--ROLE", "a"}, nil, nil, &UnknownOptionError{}},
+		{false, []string{"-x"}, nil, nil, &UnknownOptionError{}},
+		{false, []string{"-f", "--role"}, nil, nil, &MissingArgumentError{}},
+	}
+	for _, c := range cases {
+		var options []string
+		positionals, err := fields.SplitOptions(c.fold, func(field *ArgumentStructField, value string) {
+			options = append(options, field.Name+"="+value)
+		}, c.args...)
+		if c.err != nil {
+			if reflect.TypeOf(err) != reflect.TypeOf(c.err) {
+				t.Errorf("SplitOptions(%q) error = %v, want %T", c.args, err, c.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(options, c.options) || !reflect.DeepEqual(positionals, c.positionals) {
+			t.Errorf("SplitOptions(%q) = %q, %q, %v, want %q, %q", c.args, options, positionals, err, c.options, c.positionals)
+		}
+	}
+}
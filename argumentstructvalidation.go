@@ -0,0 +1,267 @@
+package xstrings
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// validateFields checks the validation constraints of all fields of the struct which val points to,
+// including the fields which are not supplied by arguments.
+func (a *ArgumentStruct) validateFields(val reflect.Value) error {
+	if a.ValidateTagKey == "" {
+		return nil
+	}
+	var err error
+	e := a.fieldsFunc(val, true, func(fieldName string, sf reflect.StructField, fieldVal reflect.Value) bool {
+		err = a.validateField(fieldVal, fieldName, sf)
+		return err != nil
+	})
+	if e != nil {
+		return e
+	}
+	return err
+}
+
+// validateField checks the validation constraints of the field in the struct tag.
+// Supported constraints are:
+//
+//	nonempty            value is not empty or zero
+//	len=n               length of string (in runes), array, slice or map is n
+//	minlen=n, maxlen=n  length bounds of string (in runes), array, slice or map
+//	min=x, max=x        bounds of numbers, x is parsed as the field type widened to 64 bits by the codec options of the field
+//	regexp=pattern      string matches pattern
+//	oneof=a|b|c         value equals one of the alternatives, which are parsed as the field type
+//
+// min, max, regexp and oneof are applied to each element of arrays and slices.
+// A comma in a constraint can be escaped by backslash. Nil pointers only fail nonempty.
+// If ValidateTagKey is empty, the field is not validated.
+func (a *ArgumentStruct) validateField(val reflect.Value, name string, sf reflect.StructField) error {
+	if a.ValidateTagKey == "" {
+		return nil
+	}
+
+	tag := sf.Tag.Get(a.ValidateTagKey)
+	if tag == "" {
+		return nil
+	}
+
+	unmarshaler, err := a.getUnmarshaler(sf)
+	if err != nil {
+		return &ArgumentValidationError{name, tag, nil, err}
+	}
+
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+
+	for _, constraint := range splitConstraints(tag) {
+		if constraint == "" {
+			continue
+		}
+		if err := a.validateConstraint(unmarshaler, val, name, constraint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *ArgumentStruct) validateConstraint(unmarshaler *Unmarshaler, val reflect.Value, name string, constraint string) error {
+	key, arg := constraint, ""
+	if idx := strings.Index(constraint, "="); idx >= 0 {
+		key, arg = constraint[:idx], constraint[idx+1:]
+	}
+
+	newErr := func(value interface{}, err error) error {
+		return &ArgumentValidationError{name, constraint, value, err}
+	}
+
+	if key == "nonempty" {
+		if isEmptyValue(val) {
+			return newErr(val.Interface(), errors.New("value is empty"))
+		}
+		return nil
+	}
+
+	if val.Kind() == reflect.Ptr {
+		return nil
+	}
+
+	switch key {
+	case "len", "minlen", "maxlen":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return newErr(nil, ErrInvalidConstraint)
+		}
+		var l int
+		switch val.Kind() {
+		case reflect.String:
+			l = utf8.RuneCountInString(val.String())
+		case reflect.Array, reflect.Slice, reflect.Map:
+			l = val.Len()
+		default:
+			return newErr(nil, ErrInvalidConstraint)
+		}
+		switch {
+		case key == "len" && l != n:
+			return newErr(val.Interface(), fmt.Errorf("length %d is not %d", l, n))
+		case key == "minlen" && l < n:
+			return newErr(val.Interface(), fmt.Errorf("length %d is less than %d", l, n))
+		case key == "maxlen" && l > n:
+			return newErr(val.Interface(), fmt.Errorf("length %d is greater than %d", l, n))
+		}
+		return nil
+
+	case "min", "max", "regexp", "oneof":
+		if val.Kind() != reflect.Array && val.Kind() != reflect.Slice {
+			return a.validateElement(unmarshaler, val, key, arg, newErr)
+		}
+		for i, j := 0, val.Len(); i < j; i++ {
+			if err := a.validateElement(unmarshaler, val.Index(i), key, arg, newErr); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	}
+
+	return newErr(nil, ErrInvalidConstraint)
+}
+
+// validateElement checks the constraint of val. The arguments of the constraint are parsed by unmarshaler,
+// which is overridden by the codec options of the field.
+func (a *ArgumentStruct) validateElement(unmarshaler *Unmarshaler, val reflect.Value, key, arg string, newErr func(value interface{}, err error) error) error {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+
+	switch key {
+	case "min", "max":
+		bound, err := unmarshaler.ParseToValue(arg, getBoundType(val.Type()))
+		if err != nil {
+			return newErr(nil, ErrInvalidConstraint)
+		}
+		cmp, ok := compareNumbers(val, bound)
+		if !ok {
+			return newErr(nil, ErrInvalidConstraint)
+		}
+		if key == "min" && cmp < 0 {
+			return newErr(val.Interface(), fmt.Errorf("%v is less than %s", val.Interface(), arg))
+		}
+		if key == "max" && cmp > 0 {
+			return newErr(val.Interface(), fmt.Errorf("%v is greater than %s", val.Interface(), arg))
+		}
+
+	case "regexp":
+		if val.Kind() != reflect.String {
+			return newErr(nil, ErrInvalidConstraint)
+		}
+		re, err := compileRegexp(arg)
+		if err != nil {
+			return newErr(nil, ErrInvalidConstraint)
+		}
+		if !re.MatchString(val.String()) {
+			return newErr(val.Interface(), fmt.Errorf("%q does not match %s", val.String(), arg))
+		}
+
+	case "oneof":
+		for _, alt := range strings.Split(arg, "|") {
+			altVal, err := unmarshaler.ParseToValue(alt, val.Type())
+			if err != nil {
+				continue
+			}
+			if reflect.DeepEqual(val.Interface(), altVal.Interface()) {
+				return nil
+			}
+		}
+		return newErr(val.Interface(), fmt.Errorf("%v is not one of %s", val.Interface(), arg))
+
+	}
+
+	return nil
+}
+
+// splitConstraints splits str by the commas which are not escaped by backslash.
+func splitConstraints(str string) []string {
+	result := make([]string, 0, 8)
+	buf := make([]byte, 0, len(str))
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == '\\' && i+1 < len(str) && str[i+1] == ',':
+			buf = append(buf, ',')
+			i++
+		case c == ',':
+			result = append(result, string(buf))
+			buf = buf[:0]
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(result, string(buf))
+}
+
+// getBoundType returns the type which min and max bounds of typ are parsed as, to prevent them from overflowing.
+func getBoundType(typ reflect.Type) reflect.Type {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return reflect.TypeOf(int64(0))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uintptr:
+		return reflect.TypeOf(uint64(0))
+	case reflect.Float32:
+		return reflect.TypeOf(float64(0))
+	}
+	return typ
+}
+
+// regexpCache caches the compiled patterns of regexp constraints by pattern.
+var regexpCache sync.Map
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexpCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(pattern, re)
+	return re, nil
+}
+
+func compareNumbers(x, y reflect.Value) (int, bool) {
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(x.Int() < y.Int(), x.Int() > y.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return compareOrdered(x.Uint() < y.Uint(), x.Uint() > y.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(x.Float() < y.Float(), x.Float() > y.Float()), true
+	}
+	return 0, false
+}
+
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func isEmptyValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.String, reflect.Array, reflect.Slice, reflect.Map:
+		return val.Len() <= 0
+	}
+	return val.IsZero()
+}
@@ -0,0 +1,91 @@
+package xstrings
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestArgumentStructValidation(t *testing.T) {
+	type args struct {
+		Name *string  `check:"nonempty"`
+		N    int8     `check:"min=-5,max=300"`
+		Mode string   `check:"oneof=fast|slow" validate:"required,email"`
+		Tags []string `check:"maxlen=2,regexp=^[a-z]+$"`
+	}
+	a := &ArgumentStruct{ValidateTagKey: "check"}
+	cases := []struct {
+		args       []string
+		constraint string
+	}{
+		{[]string{"x", "100", "fast", "a"}, ""},
+		{[]string{"", "100", "fast", "a"}, "nonempty"},
+		{[]string{"x", "-6", "fast", "a"}, "min=-5"},
+		{[]string{"x", "0", "medium", "a"}, "oneof=fast|slow"},
+		{[]string{"x", "0", "fast", "a", "b", "c"}, "maxlen=2"},
+		{[]string{"x", "0", "fast", "a", "B"}, "regexp=^[a-z]+$"},
+	}
+	for _, c := range cases {
+		var x args
+		err := a.Unmarshal(&x, c.args...)
+		if c.constraint == "" {
+			if err != nil {
+				t.Errorf("Unmarshal(%q) error: %v", c.args, err)
+			}
+			continue
+		}
+		var e *ArgumentValidationError
+		if !errors.As(err, &e) || e.Constraint() != c.constraint {
+			t.Errorf("Unmarshal(%q) error = %v, want validation error on %q", c.args, err, c.constraint)
+		}
+	}
+
+	var x args
+	if err := (&ArgumentStruct{}).Unmarshal(&x, "", "100", "medium"); err != nil {
+		t.Errorf("Unmarshal without ValidateTagKey error: %v", err)
+	}
+}
+
+func TestArgumentStructValidationMissing(t *testing.T) {
+	type args struct {
+		Name string `check:"nonempty"`
+		Role string `option:"role" check:"nonempty"`
+		Mode string `check:"nonempty" default:"fast"`
+	}
+	a := &ArgumentStruct{ValidateTagKey: "check"}
+	cases := []struct {
+		args []string
+		name string
+	}{
+		{[]string{"--role", "admin", "x"}, ""},
+		{[]string{"--role", "admin"}, "Name"},
+		{[]string{"x"}, "Role"},
+		{[]string{"--role", "admin", "x", ""}, "Mode"},
+	}
+	for _, c := range cases {
+		var x args
+		err := a.Unmarshal(&x, c.args...)
+		if c.name == "" {
+			if err != nil {
+				t.Errorf("Unmarshal(%q) error: %v", c.args, err)
+			}
+			continue
+		}
+		var e *ArgumentValidationError
+		if !errors.As(err, &e) || e.Name() != c.name || e.Constraint() != "nonempty" {
+			t.Errorf("Unmarshal(%q) error = %v, want validation error of %s on nonempty", c.args, err, c.name)
+		}
+	}
+}
+
+func TestCompileRegexp(t *testing.T) {
+	re, err := compileRegexp("^[a-z]+$")
+	if err != nil {
+		t.Fatalf("compileRegexp error: %v", err)
+	}
+	if re2, _ := compileRegexp("^[a-z]+$"); re2 != re {
+		t.Errorf("compileRegexp doesn't return the cached pattern")
+	}
+	if _, err := compileRegexp("["); err == nil {
+		t.Errorf("compileRegexp of invalid pattern doesn't return error")
+	}
+}
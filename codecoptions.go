@@ -0,0 +1,255 @@
+package xstrings
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithOptions returns a copy of u which is overridden by the comma separated options, e.g. "base=16,sep=;".
+// Supported options are:
+//
+//	base=n       IntBase
+//	layout=l     TimeLayout
+//	unix=unit    TimeUnix: s, ms, us or ns
+//	sep=s        CollectionSep, "sep=," sets comma
+//	kvsep=s      KeyValueSep
+//	prec=n       FloatPrec and ComplexPrec of Marshaler, ignored by Unmarshaler
+//	fmt=c        FloatFmt and ComplexFmt of Marshaler, ignored by Unmarshaler
+//
+// A comma in an option value can be escaped by backslash.
+func (u *Unmarshaler) WithOptions(options string) (*Unmarshaler, error) {
+	result := *u
+	err := parseCodecOptions(options, func(key, value string) error {
+		switch key {
+		case "base":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			result.IntBase = n
+		case "layout":
+			result.TimeLayout = value
+		case "unix":
+			if _, ok := timeUnits[value]; !ok {
+				return ErrUnknownTimeUnit
+			}
+			result.TimeUnix = value
+		case "sep":
+			result.CollectionSep = value
+		case "kvsep":
+			result.KeyValueSep = value
+		case "prec", "fmt":
+		default:
+			return ErrUnknownCodecOption
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// WithOptions returns a copy of m which is overridden by the comma separated options.
+// See Unmarshaler.WithOptions for the supported options.
+func (m *Marshaler) WithOptions(options string) (*Marshaler, error) {
+	result := *m
+	err := parseCodecOptions(options, func(key, value string) error {
+		switch key {
+		case "base":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			result.IntBase = n
+		case "layout":
+			result.TimeLayout = value
+		case "unix":
+			if _, ok := timeUnits[value]; !ok {
+				return ErrUnknownTimeUnit
+			}
+			result.TimeUnix = value
+		case "sep":
+			result.CollectionSep = value
+		case "kvsep":
+			result.KeyValueSep = value
+		case "prec":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			result.FloatPrec, result.ComplexPrec = n, n
+		case "fmt":
+			if len(value) != 1 {
+				return fmt.Errorf("invalid format %q", value)
+			}
+			result.FloatFmt, result.ComplexFmt = value[0], value[0]
+		default:
+			return ErrUnknownCodecOption
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UnmarshalStructField parses str into the field named fieldName of the struct which ifc points to.
+// The options in the struct tag of the field with key codecTagKey override u for the field.
+// If codecTagKey is empty, DefaultCodecTagKey is used.
+// fieldName is the Go name of the field, as in reflect.Type.FieldByName, so the fields of embedded structs
+// are found by their own names. Struct tag names, e.g. ArgumentStruct.FieldTagKey, are not taken into account.
+func (u *Unmarshaler) UnmarshalStructField(str string, ifc interface{}, fieldName string, codecTagKey string) error {
+	fieldVal, sf, err := getStructField(reflect.ValueOf(ifc), fieldName)
+	if err != nil {
+		return err
+	}
+	u2, err := getFieldUnmarshaler(u, codecTagKey, sf)
+	if err != nil {
+		return newParseError(err)
+	}
+	return u2.UnmarshalByValue(str, fieldVal)
+}
+
+// MarshalStructField formats the field named fieldName of the struct which ifc is or points to.
+// The options in the struct tag of the field with key codecTagKey override m for the field.
+// See Unmarshaler.UnmarshalStructField for codecTagKey and fieldName.
+func (m *Marshaler) MarshalStructField(ifc interface{}, fieldName string, codecTagKey string) (string, error) {
+	fieldVal, sf, err := getStructField(reflect.ValueOf(ifc), fieldName)
+	if err != nil {
+		return "", err
+	}
+	m2, err := getFieldMarshaler(m, codecTagKey, sf)
+	if err != nil {
+		return "", newFormatError(err)
+	}
+	return m2.MarshalByValue(fieldVal)
+}
+
+// getFieldUnmarshaler returns unmarshaler which is overridden by the codec options of the field sf in the tag
+// codecTagKey. If unmarshaler is nil, NewUnmarshaler is used. If codecTagKey is empty, DefaultCodecTagKey is used.
+func getFieldUnmarshaler(unmarshaler *Unmarshaler, codecTagKey string, sf reflect.StructField) (*Unmarshaler, error) {
+	if unmarshaler == nil {
+		unmarshaler = NewUnmarshaler()
+	}
+	options := sf.Tag.Get(getCodecTagKey(codecTagKey))
+	if options == "" {
+		return unmarshaler, nil
+	}
+	return unmarshaler.WithOptions(options)
+}
+
+// getFieldMarshaler returns marshaler which is overridden by the codec options of the field sf.
+// See getFieldUnmarshaler.
+func getFieldMarshaler(marshaler *Marshaler, codecTagKey string, sf reflect.StructField) (*Marshaler, error) {
+	if marshaler == nil {
+		marshaler = NewMarshaler()
+	}
+	options := sf.Tag.Get(getCodecTagKey(codecTagKey))
+	if options == "" {
+		return marshaler, nil
+	}
+	return marshaler.WithOptions(options)
+}
+
+func getCodecTagKey(codecTagKey string) string {
+	if codecTagKey == "" {
+		return DefaultCodecTagKey
+	}
+	return codecTagKey
+}
+
+func getStructField(val reflect.Value, fieldName string) (reflect.Value, reflect.StructField, error) {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Value{}, reflect.StructField{}, ErrNilPointer
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return reflect.Value{}, reflect.StructField{}, ErrValueMustBeStruct
+	}
+	sf, ok := val.Type().FieldByName(fieldName)
+	if !ok {
+		return reflect.Value{}, reflect.StructField{}, ErrArgumentStructFieldNotFound
+	}
+	fieldVal := val
+	for idx, i := range sf.Index {
+		if idx > 0 && fieldVal.Kind() == reflect.Ptr {
+			if fieldVal.IsNil() {
+				return reflect.Value{}, reflect.StructField{}, ErrNilPointer
+			}
+			fieldVal = fieldVal.Elem()
+		}
+		fieldVal = fieldVal.Field(i)
+	}
+	return fieldVal, sf, nil
+}
+
+// parseCodecOptions calls f for each option in options. An empty value which is followed by
+// an empty option, as in "sep=,", is a comma.
+func parseCodecOptions(options string, f func(key, value string) error) error {
+	items := splitConstraints(options)
+	for idx := 0; idx < len(items); idx++ {
+		item := items[idx]
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		key, value := item, ""
+		if i := strings.Index(item, "="); i >= 0 {
+			key, value = item[:i], item[i+1:]
+			if value == "" && idx+1 < len(items) && items[idx+1] == "" {
+				value = ","
+				idx++
+			}
+		}
+		key = strings.TrimSpace(key)
+		if err := f(key, value); err != nil {
+			return fmt.Errorf("codec option %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+var timeUnits = map[string]struct{}{
+	"s":  {},
+	"ms": {},
+	"us": {},
+	"ns": {},
+}
+
+func formatUnixTime(t time.Time, unit string) (string, error) {
+	switch unit {
+	case "s":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "ms":
+		return strconv.FormatInt(t.Unix()*1e3+int64(t.Nanosecond())/1e6, 10), nil
+	case "us":
+		return strconv.FormatInt(t.Unix()*1e6+int64(t.Nanosecond())/1e3, 10), nil
+	case "ns":
+		return strconv.FormatInt(t.UnixNano(), 10), nil
+	}
+	return "", ErrUnknownTimeUnit
+}
+
+func parseUnixTime(str string, unit string) (time.Time, error) {
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch unit {
+	case "s":
+		return time.Unix(n, 0), nil
+	case "ms":
+		return time.Unix(n/1e3, (n%1e3)*1e6), nil
+	case "us":
+		return time.Unix(n/1e6, (n%1e6)*1e3), nil
+	case "ns":
+		return time.Unix(0, n), nil
+	}
+	return time.Time{}, ErrUnknownTimeUnit
+}
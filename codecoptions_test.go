@@ -0,0 +1,143 @@
+package xstrings
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type codecOptionsStruct struct {
+	Mask    uint32            `xstrings:"base=16" check:"max=ff"`
+	Tags    []string          `xstrings:"sep=,"`
+	Labels  map[string]string `xstrings:"sep=;,kvsep=:"`
+	Created time.Time         `xstrings:"unix=ms"`
+	Ratio   float64           `xstrings:"prec=2,fmt=f"`
+	Bad     int               `xstrings:"base=x"`
+	Custom  uint8             `xstrings:"base=2" codec:"base=16"`
+	*codecOptionsEmbedded
+}
+
+type codecOptionsEmbedded struct {
+	Inner int `xstrings:"base=2"`
+}
+
+func TestCodecOptionsStructField(t *testing.T) {
+	u, m := NewUnmarshaler(), NewMarshaler()
+	cases := []struct {
+		field string
+		str   string
+	}{
+		{"Mask", "ff"},
+		{"Tags", "a,b\\,c"},
+		{"Labels", "k1:v1;k2:v2"},
+		{"Created", "1500000000123"},
+		{"Ratio", "0.50"},
+	}
+	x := &codecOptionsStruct{}
+	for _, c := range cases {
+		if err := u.UnmarshalStructField(c.str, x, c.field, ""); err != nil {
+			t.Errorf("UnmarshalStructField(%q, %s) error: %v", c.str, c.field, err)
+			continue
+		}
+		str, err := m.MarshalStructField(x, c.field, "")
+		if err != nil || str != c.str {
+			t.Errorf("MarshalStructField(%s) = %q, %v, want %q", c.field, str, err, c.str)
+		}
+	}
+	want := &codecOptionsStruct{
+		Mask:    0xff,
+		Tags:    []string{"a", "b,c"},
+		Labels:  map[string]string{"k1": "v1", "k2": "v2"},
+		Created: time.Unix(1500000000, 123e6),
+		Ratio:   0.5,
+	}
+	if !reflect.DeepEqual(x, want) {
+		t.Errorf("struct = %+v, want %+v", x, want)
+	}
+
+	var e *ParseError
+	if err := u.UnmarshalStructField("1", x, "Bad", ""); !errors.As(err, &e) {
+		t.Errorf("UnmarshalStructField with bad options error = %v, want *ParseError", err)
+	}
+	if err := u.UnmarshalStructField("1", x, "Inner", ""); !errors.Is(err, ErrNilPointer) {
+		t.Errorf("UnmarshalStructField of nil embedded struct error = %v, want %v", err, ErrNilPointer)
+	}
+	x.codecOptionsEmbedded = &codecOptionsEmbedded{}
+	if err := u.UnmarshalStructField("101", x, "Inner", ""); err != nil || x.Inner != 5 {
+		t.Errorf("UnmarshalStructField(Inner) = %d, %v, want 5", x.Inner, err)
+	}
+	if err := u.UnmarshalStructField("ff", x, "Custom", "codec"); err != nil || x.Custom != 0xff {
+		t.Errorf("UnmarshalStructField(Custom) with codec tag key = %d, %v, want 255", x.Custom, err)
+	}
+	if str, err := m.MarshalStructField(x, "Custom", ""); err != nil || str != "11111111" {
+		t.Errorf("MarshalStructField(Custom) = %q, %v, want %q", str, err, "11111111")
+	}
+	if err := u.UnmarshalStructField("1", x, "Missing", ""); !errors.Is(err, ErrArgumentStructFieldNotFound) {
+		t.Errorf("UnmarshalStructField of missing field error = %v", err)
+	}
+}
+
+func TestCodecOptionsError(t *testing.T) {
+	cases := []struct {
+		options string
+		err     error
+	}{
+		{"unknown=1", ErrUnknownCodecOption},
+		{"unix=m", ErrUnknownTimeUnit},
+		{"base=16, sep=,", nil},
+		{"layout=2006-01-02\\, Mon", nil},
+	}
+	for _, c := range cases {
+		_, err := NewUnmarshaler().WithOptions(c.options)
+		if !errors.Is(err, c.err) || (c.err == nil) != (err == nil) {
+			t.Errorf("WithOptions(%q) error = %v, want %v", c.options, err, c.err)
+		}
+	}
+	u, _ := NewUnmarshaler().WithOptions("layout=2006-01-02\\, Mon,sep=,")
+	if u.TimeLayout != "2006-01-02, Mon" || u.CollectionSep != "," {
+		t.Errorf("WithOptions = %q, %q", u.TimeLayout, u.CollectionSep)
+	}
+}
+
+func TestArgumentStructCodecOptions(t *testing.T) {
+	type args struct {
+		Mask uint32   `xstrings:"base=16" check:"max=ff"`
+		Tags []string `xstrings:"sep=,"`
+	}
+	a := &ArgumentStruct{ValidateTagKey: "check"}
+	var x args
+	if err := a.Unmarshal(&x, "ff", "a,b", "c"); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if want := (args{0xff, []string{"a", "b", "c"}}); !reflect.DeepEqual(x, want) {
+		t.Errorf("Unmarshal = %+v, want %+v", x, want)
+	}
+	var e *ArgumentValidationError
+	if err := a.Unmarshal(&x, "100"); !errors.As(err, &e) || e.Constraint() != "max=ff" {
+		t.Errorf("Unmarshal error = %v, want validation error on max=ff", err)
+	}
+	argv, err := a.Marshal(&args{0xab, []string{"a", "b"}})
+	if err != nil || !reflect.DeepEqual(argv, []string{"ab", "a,b"}) {
+		t.Errorf("Marshal = %q, %v", argv, err)
+	}
+}
+
+func TestUnmarshalerUintBase(t *testing.T) {
+	cases := []struct {
+		u    *Unmarshaler
+		str  string
+		want uint
+	}{
+		{&Unmarshaler{}, "010", 10},
+		{NewUnmarshaler(), "010", 10},
+		{&Unmarshaler{IntBase: 16}, "10", 16},
+		{&Unmarshaler{IntBase: 8}, "10", 8},
+	}
+	for _, c := range cases {
+		var x uint
+		if err := c.u.Unmarshal(c.str, &x); err != nil || x != c.want {
+			t.Errorf("IntBase %d: Unmarshal(%q) = %d, %v, want %d", c.u.IntBase, c.str, x, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package xstrings
+
+import (
+	"reflect"
+	"sync"
+)
+
+// EncodeFunc formats val into string.
+type EncodeFunc func(val reflect.Value) (string, error)
+
+// DecodeFunc parses str into val, which is settable.
+type DecodeFunc func(str string, val reflect.Value) error
+
+// CodecRegistry holds encode and decode functions per type, which are consulted by Marshaler and Unmarshaler
+// before their built-in handling. The functions of an interface type are used for the types which implement
+// the interface, if there is no function of the exact type. Decode functions of an interface type are also used
+// for the types which implement the interface by pointer receiver, because the value which is decoded is addressable.
+type CodecRegistry struct {
+	mu    sync.RWMutex
+	types map[reflect.Type]*typeCodec
+	ifcs  []*typeCodec
+}
+
+type typeCodec struct {
+	typ    reflect.Type
+	encode EncodeFunc
+	decode DecodeFunc
+}
+
+func NewCodecRegistry() *CodecRegistry {
+	return &CodecRegistry{
+		types: make(map[reflect.Type]*typeCodec),
+	}
+}
+
+// Register registers encode and decode functions of typ. Either of them can be nil to handle only one direction.
+// Registering a type again replaces its functions. Pointers are dereferenced before looking up, so typ shouldn't be a pointer.
+func (r *CodecRegistry) Register(typ reflect.Type, encode EncodeFunc, decode DecodeFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := &typeCodec{
+		typ:    typ,
+		encode: encode,
+		decode: decode,
+	}
+
+	if typ.Kind() == reflect.Interface {
+		for idx, c2 := range r.ifcs {
+			if c2.typ == typ {
+				r.ifcs[idx] = c
+				return
+			}
+		}
+		r.ifcs = append(r.ifcs, c)
+		return
+	}
+
+	if r.types == nil {
+		r.types = make(map[reflect.Type]*typeCodec)
+	}
+	r.types[typ] = c
+}
+
+// Encoder returns the encode function of typ.
+func (r *CodecRegistry) Encoder(typ reflect.Type) (EncodeFunc, bool) {
+	c := r.lookup(typ, false, func(c *typeCodec) bool {
+		return c.encode != nil
+	})
+	if c == nil {
+		return nil, false
+	}
+	return c.encode, true
+}
+
+// Decoder returns the decode function of typ.
+func (r *CodecRegistry) Decoder(typ reflect.Type) (DecodeFunc, bool) {
+	c := r.lookup(typ, true, func(c *typeCodec) bool {
+		return c.decode != nil
+	})
+	if c == nil {
+		return nil, false
+	}
+	return c.decode, true
+}
+
+// lookup returns the codec of typ which f accepts. If ptrRecv, interfaces which are implemented by pointer receiver match.
+func (r *CodecRegistry) lookup(typ reflect.Type, ptrRecv bool, f func(c *typeCodec) bool) *typeCodec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if c, ok := r.types[typ]; ok && f(c) {
+		return c
+	}
+	for _, c := range r.ifcs {
+		if !f(c) {
+			continue
+		}
+		if typ.Implements(c.typ) || (ptrRecv && typ.Kind() != reflect.Ptr && reflect.PtrTo(typ).Implements(c.typ)) {
+			return c
+		}
+	}
+	return nil
+}
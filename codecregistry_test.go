@@ -0,0 +1,56 @@
+package xstrings
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ptrStringer struct {
+	s string
+}
+
+func (p *ptrStringer) String() string {
+	return p.s
+}
+
+func (p *ptrStringer) Set(s string) {
+	p.s = s
+}
+
+type valueStringer struct {
+	s string
+}
+
+func (v valueStringer) String() string {
+	return v.s
+}
+
+func TestCodecRegistry(t *testing.T) {
+	type setter interface {
+		Set(s string)
+	}
+	r := NewCodecRegistry()
+	r.Register(reflect.TypeOf((*fmt.Stringer)(nil)).Elem(), func(val reflect.Value) (string, error) {
+		return strings.ToUpper(val.Interface().(fmt.Stringer).String()), nil
+	}, nil)
+	r.Register(reflect.TypeOf((*setter)(nil)).Elem(), nil, func(str string, val reflect.Value) error {
+		val.Addr().Interface().(setter).Set(strings.ToLower(str))
+		return nil
+	})
+
+	m := &Marshaler{Codecs: r}
+	if str, err := m.Marshal(valueStringer{"abc"}); err != nil || str != "ABC" {
+		t.Errorf("Marshal(valueStringer) = %q, %v, want %q", str, err, "ABC")
+	}
+	if str, err := m.Marshal(&ptrStringer{"abc"}); err != nil || str == "ABC" {
+		t.Errorf("Marshal(*ptrStringer) = %q, %v, want the built-in handling", str, err)
+	}
+
+	u := &Unmarshaler{Codecs: r}
+	var p ptrStringer
+	if err := u.Unmarshal("ABC", &p); err != nil || p.s != "abc" {
+		t.Errorf("Unmarshal(*ptrStringer) = %q, %v, want %q", p.s, err, "abc")
+	}
+}
@@ -0,0 +1,184 @@
+package xstrings
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// marshalCollection formats the array, slice or map val as delimited collection.
+// The elements are formatted by m, and the runes of the separators in them are escaped by backslash.
+func (m *Marshaler) marshalCollection(val reflect.Value) (string, error) {
+	sep, kvSep := m.CollectionSep, m.KeyValueSep
+	if kvSep == "" {
+		kvSep = DefaultKeyValueSep
+	}
+
+	marshal := func(val reflect.Value) (string, error) {
+		str, err := m.MarshalByValue(val)
+		if err != nil {
+			if e, ok := err.(*FormatError); ok {
+				err = e.Unwrap()
+			}
+			return "", err
+		}
+		return escapeCollectionElement(str, sep, kvSep), nil
+	}
+
+	if val.Kind() != reflect.Map {
+		elems := make([]string, 0, val.Len())
+		for i, j := 0, val.Len(); i < j; i++ {
+			elem, err := marshal(val.Index(i))
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, elem)
+		}
+		if len(elems) == 1 && elems[0] == "" {
+			return "\\", nil
+		}
+		return strings.Join(elems, sep), nil
+	}
+
+	elems := make([]string, 0, val.Len())
+	iter := val.MapRange()
+	for iter.Next() {
+		key, err := marshal(iter.Key())
+		if err != nil {
+			return "", err
+		}
+		value, err := marshal(iter.Value())
+		if err != nil {
+			return "", err
+		}
+		elems = append(elems, key+kvSep+value)
+	}
+	sort.Strings(elems)
+	return strings.Join(elems, sep), nil
+}
+
+// unmarshalCollection parses the delimited collection str into the array, slice or map val.
+// The elements are parsed by u after their escapes are removed.
+func (u *Unmarshaler) unmarshalCollection(str string, val reflect.Value) error {
+	sep, kvSep := u.CollectionSep, u.KeyValueSep
+	if kvSep == "" {
+		kvSep = DefaultKeyValueSep
+	}
+
+	typ := val.Type()
+	unmarshal := func(str string, typ reflect.Type) (reflect.Value, error) {
+		elemVal, err := u.ParseToValue(unescapeCollectionElement(str), typ)
+		if err != nil {
+			if e, ok := err.(*ParseError); ok {
+				err = e.Unwrap()
+			}
+			return reflect.Value{}, err
+		}
+		return elemVal, nil
+	}
+
+	var elems []string
+	if str != "" {
+		elems = splitCollection(str, sep)
+	}
+
+	switch typ.Kind() {
+	case reflect.Array:
+		if len(elems) > typ.Len() {
+			return fmt.Errorf("%d elements exceed array length %d", len(elems), typ.Len())
+		}
+		result := reflect.New(typ).Elem()
+		for idx, elem := range elems {
+			elemVal, err := unmarshal(elem, typ.Elem())
+			if err != nil {
+				return err
+			}
+			result.Index(idx).Set(elemVal)
+		}
+		val.Set(result)
+
+	case reflect.Slice:
+		result := reflect.MakeSlice(typ, 0, len(elems))
+		for _, elem := range elems {
+			elemVal, err := unmarshal(elem, typ.Elem())
+			if err != nil {
+				return err
+			}
+			result = reflect.Append(result, elemVal)
+		}
+		val.Set(result)
+
+	case reflect.Map:
+		result := reflect.MakeMapWithSize(typ, len(elems))
+		for _, elem := range elems {
+			kv := splitCollection(elem, kvSep)
+			if len(kv) < 2 {
+				return fmt.Errorf("element %q: %w", elem, ErrMissingKeyValueSep)
+			}
+			keyVal, err := unmarshal(kv[0], typ.Key())
+			if err != nil {
+				return err
+			}
+			valueVal, err := unmarshal(elem[len(kv[0])+len(kvSep):], typ.Elem())
+			if err != nil {
+				return err
+			}
+			result.SetMapIndex(keyVal, valueVal)
+		}
+		val.Set(result)
+
+	}
+
+	return nil
+}
+
+// escapeCollectionElement escapes backslashes and the runes of seps in str by backslash.
+func escapeCollectionElement(str string, seps ...string) string {
+	runes := strings.Join(seps, "")
+	b := &strings.Builder{}
+	for _, r := range str {
+		if r == '\\' || strings.ContainsRune(runes, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unescapeCollectionElement removes the escaping backslashes from str.
+func unescapeCollectionElement(str string) string {
+	b := &strings.Builder{}
+	escaped := false
+	for _, r := range str {
+		if !escaped && r == '\\' {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// splitCollection splits str by sep which is not escaped by backslash. The escapes are kept in the result.
+func splitCollection(str, sep string) []string {
+	result := make([]string, 0, 16)
+	start := 0
+	for i := 0; i < len(str); {
+		switch {
+		case str[i] == '\\':
+			i += 2
+		case strings.HasPrefix(str[i:], sep):
+			result = append(result, str[start:i])
+			i += len(sep)
+			start = i
+		default:
+			i++
+		}
+	}
+	if start > len(str) {
+		start = len(str)
+	}
+	return append(result, str[start:])
+}
@@ -0,0 +1,72 @@
+package xstrings
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCollection(t *testing.T) {
+	cases := []struct {
+		sep, kvSep string
+		value      interface{}
+		str        string
+	}{
+		{",", "", []string{"a", "b", "c"}, "a,b,c"},
+		{",", "", []string{}, ""},
+		{",", "", []string{""}, "\\"},
+		{",", "", []string{"", ""}, ","},
+		{",", "", []string{"a,b", "c\\d", ""}, "a\\,b,c\\\\d,"},
+		{",", "", []int{1, -2, 3}, "1,-2,3"},
+		{",", "", [3]int{1, 2, 0}, "1,2,0"},
+		{",", "", [1]string{""}, "\\"},
+		{"::", "", []string{"a:b", "c"}, "a\\:b::c"},
+		{";", "", map[string]int{"b": 2, "a": 1}, "a=1;b=2"},
+		{";", ":", map[string]string{"k=1": "v;2", "": ""}, ":;k=1:v\\;2"},
+		{";", "", map[string]string{}, ""},
+	}
+	for _, c := range cases {
+		m := NewMarshaler()
+		m.CollectionSep, m.KeyValueSep = c.sep, c.kvSep
+		str, err := m.Marshal(c.value)
+		if err != nil || str != c.str {
+			t.Errorf("Marshal(%#v) = %q, %v, want %q", c.value, str, err, c.str)
+		}
+		u := NewUnmarshaler()
+		u.CollectionSep, u.KeyValueSep = c.sep, c.kvSep
+		ptr := reflect.New(reflect.TypeOf(c.value))
+		if err := u.Unmarshal(c.str, ptr.Interface()); err != nil {
+			t.Errorf("Unmarshal(%q) error: %v", c.str, err)
+			continue
+		}
+		if got := ptr.Elem().Interface(); !reflect.DeepEqual(got, c.value) {
+			t.Errorf("Unmarshal(%q) = %#v, want %#v", c.str, got, c.value)
+		}
+	}
+}
+
+func TestCollectionError(t *testing.T) {
+	u := NewUnmarshaler()
+	u.CollectionSep = ","
+	cases := []struct {
+		str   string
+		value interface{}
+		err   error
+	}{
+		{"a=1,b", &map[string]int{}, ErrMissingKeyValueSep},
+		{"a=x", &map[string]int{}, nil},
+		{"1,x", &[]int{}, nil},
+		{"1,2,3", &[2]int{}, nil},
+	}
+	for _, c := range cases {
+		err := u.Unmarshal(c.str, c.value)
+		var e *ParseError
+		if !errors.As(err, &e) {
+			t.Errorf("Unmarshal(%q) error = %v, want *ParseError", c.str, err)
+			continue
+		}
+		if c.err != nil && !errors.Is(err, c.err) {
+			t.Errorf("Unmarshal(%q) error = %v, want %v", c.str, err, c.err)
+		}
+	}
+}
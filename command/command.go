@@ -1,5 +1,9 @@
 package command
 
+import (
+	"context"
+)
+
 type Command interface {
 	Runnable
 	Names
@@ -39,3 +43,102 @@ func (c *commandStruct) ArgCountMin() int {
 func (c *commandStruct) ArgCountMax() int {
 	return c.argCountMax
 }
+
+// Parent is the interface that is implemented by commands which own sub commands.
+type Parent interface {
+	SubCommands() []Command
+}
+
+// WithSubCommands returns a new Command which behaves like cmd and owns subCmds.
+// cmd runs when the arguments don't match any of subCmds.
+func WithSubCommands(cmd Command, subCmds ...Command) Command {
+	p := &parentStruct{
+		Command: cmd,
+		subCmds: make([]Command, len(subCmds)),
+	}
+	copy(p.subCmds, subCmds)
+	return p
+}
+
+// NewGroup returns a new Command which only owns subCmds.
+// Running it returns ErrSubCommandNotSet, and finding an unknown sub command of it returns UnknownCommandError.
+func NewGroup(names Names, subCmds ...Command) Command {
+	g := &groupStruct{
+		Names:   names,
+		subCmds: make([]Command, len(subCmds)),
+	}
+	copy(g.subCmds, subCmds)
+	return g
+}
+
+type parentStruct struct {
+	Command
+	subCmds []Command
+}
+
+func (p *parentStruct) SubCommands() []Command {
+	result := make([]Command, len(p.subCmds))
+	copy(result, p.subCmds)
+	return result
+}
+
+type groupStruct struct {
+	Names
+	subCmds []Command
+}
+
+func (g *groupStruct) Run(ctx context.Context) error {
+	return ErrSubCommandNotSet
+}
+
+func (g *groupStruct) FieldOffset() int {
+	return 0
+}
+
+func (g *groupStruct) ArgCountMin() int {
+	return 0
+}
+
+func (g *groupStruct) ArgCountMax() int {
+	return 0
+}
+
+func (g *groupStruct) SubCommands() []Command {
+	result := make([]Command, len(g.subCmds))
+	copy(result, g.subCmds)
+	return result
+}
+
+// isGroup reports whether cmd is created by NewGroup, or wraps a command which is created by NewGroup.
+func isGroup(cmd Command) bool {
+	return lookupCommand(cmd, func(ifc interface{}) bool {
+		_, ok := ifc.(*groupStruct)
+		return ok
+	})
+}
+
+// lookupCommand calls f with ifc and the values which are wrapped by it, until f returns true.
+// It reports whether f returned true.
+func lookupCommand(ifc interface{}, f func(ifc interface{}) bool) bool {
+	if ifc == nil {
+		return false
+	}
+	if f(ifc) {
+		return true
+	}
+	switch x := ifc.(type) {
+	case *parentStruct:
+		return lookupCommand(x.Command, f)
+	case *groupStruct:
+		return lookupCommand(x.Names, f)
+	case *commandStruct:
+		return lookupCommand(x.Runnable, f) || lookupCommand(x.Names, f)
+	case *runnableStruct:
+		return lookupCommand(x.UnmarshalInterface, f)
+	case *middlewareStruct:
+		return lookupCommand(x.Command, f)
+	case *middlewareParentStruct:
+		return lookupCommand(x.Command, f)
+	}
+	return false
+}
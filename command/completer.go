@@ -0,0 +1,101 @@
+package command
+
+import (
+	"errors"
+
+	"github.com/goinsane/xstrings"
+)
+
+// Completer completes partial command lines in process, e.g. for REPL.
+type Completer struct {
+	Handler   *Handler
+	Commands  []Command
+	Arguments *xstrings.Arguments
+
+	// FuncCompleteValue returns the candidates of the positional argument or option value of cmd
+	// which is bound to the field named fieldName. The candidates which don't begin with prefix are ignored.
+	FuncCompleteValue func(cmd Command, fieldName string, prefix string) []string
+}
+
+// Complete returns the completion candidates of the word at the byte offset cursor in line.
+// The candidates are formatted by Arguments, and they replace line[start:cursor].
+// If the word begins with an open quote, it is completed as the quote was closed at cursor.
+func (c *Completer) Complete(line string, cursor int) (candidates []string, start int) {
+	if cursor < 0 || cursor > len(line) {
+		cursor = len(line)
+	}
+	arguments := c.getArguments()
+
+	tokens, ok := parseCompletionTokens(arguments, line[:cursor])
+	if !ok {
+		return []string{}, cursor
+	}
+
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		words = append(words, token.Value)
+	}
+	cur := ""
+	start = cursor
+	if l := len(tokens); l > 0 && tokens[l-1].End >= cursor {
+		cur = words[l-1]
+		words = words[:l-1]
+		start = tokens[l-1].Start
+	}
+
+	candidates = make([]string, 0, 64)
+	for _, candidate := range completeWords(c.getHandler(), c.Commands, words, cur, c.FuncCompleteValue) {
+		str, err := arguments.Format(candidate)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, str)
+	}
+	return candidates, start
+}
+
+func (c *Completer) getHandler() *Handler {
+	if c.Handler == nil {
+		return &Handler{}
+	}
+	return c.Handler
+}
+
+func (c *Completer) getArguments() *xstrings.Arguments {
+	if c.Arguments == nil {
+		return &xstrings.Arguments{}
+	}
+	return c.Arguments
+}
+
+// parseCompletionTokens parses str which may end with an open quote or a trailing backslash.
+func parseCompletionTokens(arguments *xstrings.Arguments, str string) ([]xstrings.ArgumentToken, bool) {
+	tokens, err := arguments.ParseTokens(str)
+	if err == nil {
+		return tokens, true
+	}
+
+	var e *xstrings.ParseError
+	if !errors.As(err, &e) {
+		return nil, false
+	}
+	switch {
+	case errors.Is(err, xstrings.ErrUnterminatedQuote) && e.Offset() >= 0 && e.Offset() < len(str):
+		quote := str[e.Offset()]
+		if quote == '$' {
+			quote = '\''
+		}
+		tokens, err = arguments.ParseTokens(str + string(quote))
+	case errors.Is(err, xstrings.ErrTrailingBackslash):
+		tokens, err = arguments.ParseTokens(str[:len(str)-1])
+		if err == nil && len(tokens) > 0 && tokens[len(tokens)-1].End >= len(str)-1 {
+			tokens[len(tokens)-1].End = len(str)
+		}
+	default:
+		return nil, false
+	}
+	if err != nil {
+		return nil, false
+	}
+	return tokens, true
+}
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goinsane/xstrings"
+)
+
+func TestCompleter(t *testing.T) {
+	completion := newTestCompletion()
+	completion.FuncCompleteValue = func(cmd Command, fieldName string, prefix string) []string {
+		if fieldName == "Name" {
+			return []string{"alice", "bob smith"}
+		}
+		return nil
+	}
+	c := &Completer{
+		Commands:          completion.Commands,
+		Arguments:         &xstrings.Arguments{Shell: true},
+		FuncCompleteValue: completion.FuncCompleteValue,
+	}
+	cases := []struct {
+		line       string
+		cursor     int
+		candidates []string
+		start      int
+	}{
+		{"", -1, []string{"user", "Serve"}, 0},
+		{"us", -1, []string{"user"}, 0},
+		{"user ", -1, []string{"add", "del", "rm"}, 5},
+		{"user a", -1, []string{"add"}, 5},
+		{"user a del", 6, []string{"add"}, 5},
+		{"user add ", -1, []string{"alice", "'bob smith'"}, 9},
+		{"user add 'b", -1, []string{"'bob smith'"}, 9},
+		{`user add "bob s`, -1, []string{"'bob smith'"}, 9},
+		{`user add bob\`, -1, []string{"'bob smith'"}, 9},
+		{`user add bob\ s`, -1, []string{"'bob smith'"}, 9},
+		{"user add --f", -1, []string{"--force"}, 9},
+		{"user add 'a' 'b", 13, []string{}, 13},
+		{"user add 'a' 'b", 12, []string{"alice"}, 9},
+	}
+	for _, c2 := range cases {
+		cursor := c2.cursor
+		if cursor < 0 {
+			cursor = len(c2.line)
+		}
+		candidates, start := c.Complete(c2.line, cursor)
+		if !reflect.DeepEqual(candidates, c2.candidates) || start != c2.start {
+			t.Errorf("Complete(%q, %d) = %q, %d, want %q, %d", c2.line, cursor, candidates, start, c2.candidates, c2.start)
+		}
+	}
+}
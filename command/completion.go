@@ -0,0 +1,480 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/goinsane/xstrings"
+)
+
+// CompleteCommandName is the hidden command name which the completion scripts run the program with
+// to get the completion candidates of dynamic values.
+const CompleteCommandName = "__complete"
+
+// Completion generates shell completion scripts for Commands, and answers the completion requests of them.
+// The scripts complete command names, sub command names and option names statically,
+// and ask the program by CompleteCommandName for the other arguments.
+type Completion struct {
+	Handler  *Handler
+	Commands []Command
+	ProgName string
+
+	// FuncCompleteValue returns the candidates of the positional argument or option value of cmd
+	// which is bound to the field named fieldName. The candidates which don't begin with prefix are ignored.
+	FuncCompleteValue func(cmd Command, fieldName string, prefix string) []string
+}
+
+// Handle writes the completion candidates to w, one per line, if args[0] is CompleteCommandName.
+// args[1:] are the arguments after the program name, and the last one is the word being completed.
+// It reports whether args is a completion request.
+func (c *Completion) Handle(w io.Writer, args ...string) (bool, error) {
+	if len(args) <= 0 || args[0] != CompleteCommandName {
+		return false, nil
+	}
+	for _, candidate := range c.Complete(args[1:]...) {
+		if _, err := fmt.Fprintln(w, candidate); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Complete returns the completion candidates of the last one of args.
+// args are the arguments after the program name.
+func (c *Completion) Complete(args ...string) []string {
+	if len(args) <= 0 {
+		args = []string{""}
+	}
+	return completeWords(c.getHandler(), c.Commands, args[:len(args)-1], args[len(args)-1], c.FuncCompleteValue)
+}
+
+// Bash writes the bash completion script.
+func (c *Completion) Bash(w io.Writer) error {
+	fn := "_" + c.getFuncName()
+	nodes := c.getNodes()
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "# bash completion for %s\n\n", c.ProgName)
+	fmt.Fprintf(b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" node=0 consumed=1 word i\n")
+	b.WriteString("\tlocal -a candidates=()\n")
+	b.WriteString("\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	b.WriteString("\t\tword=\"${COMP_WORDS[i]}\"\n")
+	writeShellCases(b, nodes)
+	b.WriteString("\tdone\n")
+	writeShellCandidates(b, nodes)
+	b.WriteString("\tCOMPREPLY=()\n")
+	b.WriteString("\tfor word in \"${candidates[@]}\"; do\n")
+	b.WriteString("\t\t[[ \"$word\" == \"$cur\"* ]] && COMPREPLY+=(\"$word\")\n")
+	b.WriteString("\tdone\n")
+	b.WriteString("\tif ((${#COMPREPLY[@]} == 0)); then\n")
+	b.WriteString("\t\tlocal IFS=$'\\n'\n")
+	fmt.Fprintf(b, "\t\tCOMPREPLY=($(\"${COMP_WORDS[0]}\" %s \"${COMP_WORDS[@]:1:COMP_CWORD}\" 2>/dev/null))\n", CompleteCommandName)
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "complete -o default -F %s %s\n", fn, shellQuote(c.ProgName))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Zsh writes the zsh completion script. The script can be autoloaded from fpath, or sourced.
+func (c *Completion) Zsh(w io.Writer) error {
+	fn := "_" + c.getFuncName()
+	nodes := c.getNodes()
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "#compdef %s\n\n", c.ProgName)
+	fmt.Fprintf(b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${words[CURRENT]}\" node=0 consumed=1 word i\n")
+	b.WriteString("\tlocal -a candidates reply\n")
+	b.WriteString("\tfor ((i = 2; i < CURRENT; i++)); do\n")
+	b.WriteString("\t\tword=\"${words[i]}\"\n")
+	writeShellCases(b, nodes)
+	b.WriteString("\tdone\n")
+	writeShellCandidates(b, nodes)
+	b.WriteString("\tfor word in \"${candidates[@]}\"; do\n")
+	b.WriteString("\t\t[[ \"$word\" == \"$cur\"* ]] && reply+=(\"$word\")\n")
+	b.WriteString("\tdone\n")
+	b.WriteString("\tif ((${#reply} == 0)); then\n")
+	fmt.Fprintf(b, "\t\treply=(\"${(@f)$(\"${words[1]}\" %s \"${(@)words[2,CURRENT]}\" 2>/dev/null)}\")\n", CompleteCommandName)
+	b.WriteString("\t\treply=(${reply:#})\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tcompadd -- \"${reply[@]}\"\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "if [[ \"${funcstack[1]}\" == %s ]]; then\n", fn)
+	fmt.Fprintf(b, "\t%s \"$@\"\n", fn)
+	b.WriteString("else\n")
+	fmt.Fprintf(b, "\tcompdef %s %s\n", fn, shellQuote(c.ProgName))
+	b.WriteString("fi\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Fish writes the fish completion script.
+func (c *Completion) Fish(w io.Writer) error {
+	fn := "__" + c.getFuncName() + "_complete"
+	nodes := c.getNodes()
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "# fish completion for %s\n\n", c.ProgName)
+	fmt.Fprintf(b, "function %s\n", fn)
+	b.WriteString("\tset -l words (commandline -opc)\n")
+	b.WriteString("\tset -l cur (commandline -ct)\n")
+	b.WriteString("\tset -l node 0\n")
+	b.WriteString("\tset -l consumed 1\n")
+	b.WriteString("\tfor word in $words[2..-1]\n")
+	b.WriteString("\t\tset -l next\n")
+	writeFishCases(b, nodes, false, "\t\t")
+	b.WriteString("\t\tif test -z \"$next\"\n")
+	writeFishCases(b, nodes, true, "\t\t\t")
+	b.WriteString("\t\tend\n")
+	b.WriteString("\t\tif test -z \"$next\"\n")
+	b.WriteString("\t\t\tset consumed 0\n")
+	b.WriteString("\t\t\tbreak\n")
+	b.WriteString("\t\tend\n")
+	b.WriteString("\t\tset node $next\n")
+	b.WriteString("\tend\n")
+	b.WriteString("\tset -l candidates\n")
+	b.WriteString("\tif test $consumed -eq 1\n")
+	b.WriteString("\t\tswitch $node\n")
+	for _, node := range nodes {
+		if len(node.names) > 0 {
+			fmt.Fprintf(b, "\t\t\tcase %d\n", node.id)
+			fmt.Fprintf(b, "\t\t\t\tset candidates %s\n", joinQuoted(node.names, fishQuote))
+		}
+	}
+	b.WriteString("\t\tend\n")
+	b.WriteString("\tend\n")
+	b.WriteString("\tif string match -q -- '-*' $cur\n")
+	b.WriteString("\t\tswitch $node\n")
+	for _, node := range nodes {
+		if len(node.options) > 0 {
+			fmt.Fprintf(b, "\t\t\tcase %d\n", node.id)
+			fmt.Fprintf(b, "\t\t\t\tset candidates $candidates %s\n", joinQuoted(node.options, fishQuote))
+		}
+	}
+	b.WriteString("\t\tend\n")
+	b.WriteString("\tend\n")
+	b.WriteString("\tset -l reply\n")
+	b.WriteString("\tfor word in $candidates\n")
+	b.WriteString("\t\tif string match -q -- (string escape --style=wildcard -- $cur)'*' $word\n")
+	b.WriteString("\t\t\tset reply $reply $word\n")
+	b.WriteString("\t\tend\n")
+	b.WriteString("\tend\n")
+	b.WriteString("\tif test (count $reply) -eq 0\n")
+	fmt.Fprintf(b, "\t\tset reply ($words[1] %s $words[2..-1] $cur 2>/dev/null)\n", CompleteCommandName)
+	b.WriteString("\tend\n")
+	b.WriteString("\tprintf '%s\\n' $reply\n")
+	b.WriteString("end\n\n")
+	fmt.Fprintf(b, "complete -c %s -f -a '(%s)'\n", fishQuote(c.ProgName), fn)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (c *Completion) getHandler() *Handler {
+	if c.Handler == nil {
+		return &Handler{}
+	}
+	return c.Handler
+}
+
+func (c *Completion) getFuncName() string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, c.ProgName)
+}
+
+// getNodes returns the command tree as nodes. The node 0 is the root which owns Commands.
+func (c *Completion) getNodes() []*completionNode {
+	h := c.getHandler()
+	nodes := []*completionNode{{id: 0}}
+	var walk func(parent *completionNode, cmds []Command)
+	walk = func(parent *completionNode, cmds []Command) {
+		for _, cmd := range cmds {
+			node := &completionNode{id: len(nodes)}
+			nodes = append(nodes, node)
+			for _, cmdName := range cmd.CmdNames() {
+				parent.edges = append(parent.edges, completionEdge{cmdName, cmd.CmdNamesFold(), node.id})
+				parent.names = append(parent.names, cmdName)
+			}
+			if fields, err := h.getArgumentStruct(cmd).Fields(cmd); err == nil {
+				node.options = getOptionNames(fields)
+			}
+			if p, ok := cmd.(Parent); ok {
+				walk(node, p.SubCommands())
+			}
+		}
+	}
+	walk(nodes[0], c.Commands)
+	return nodes
+}
+
+type completionNode struct {
+	id      int
+	edges   []completionEdge
+	names   []string
+	options []string
+}
+
+type completionEdge struct {
+	name string
+	fold bool
+	to   int
+}
+
+// completeWords returns the completion candidates of cur which follows words.
+func completeWords(h *Handler, cmds []Command, words []string, cur string, f func(cmd Command, fieldName string, prefix string) []string) []string {
+	result := make([]string, 0, 64)
+
+	var leaf Command
+	level := cmds
+	depth := 0
+	for depth < len(words) && level != nil {
+		cmd, err := h.FindCmd(level, words[depth:]...)
+		if err != nil {
+			break
+		}
+		leaf = cmd
+		depth++
+		level = nil
+		if p, ok := cmd.(Parent); ok {
+			level = p.SubCommands()
+		}
+	}
+
+	if depth == len(words) && level != nil {
+		for _, cmd := range level {
+			for _, cmdName := range cmd.CmdNames() {
+				if hasPrefix(cmdName, cur, cmd.CmdNamesFold()) {
+					result = append(result, cmdName)
+				}
+			}
+		}
+	}
+	if leaf == nil {
+		return result
+	}
+
+	fields, err := h.getArgumentStruct(leaf).Fields(leaf)
+	if err != nil {
+		return result
+	}
+
+	args := words[depth:]
+	var pending *xstrings.ArgumentStructField
+	positionals, err := fields.SplitOptions(h.FieldNameFold, nil, args...)
+	if err != nil {
+		var e *xstrings.MissingArgumentError
+		if !errors.As(err, &e) {
+			return result
+		}
+		pending = findField(fields, e.Name())
+	}
+	positional := len(positionals)
+	endOfOptions := isEndOfOptions(h, fields, args...)
+
+	var fieldName string
+	switch {
+	case pending != nil:
+		fieldName = pending.Name
+	case !endOfOptions && strings.HasPrefix(cur, "-"):
+		for _, optionName := range getOptionNames(fields) {
+			if hasPrefix(optionName, cur, h.FieldNameFold) {
+				result = append(result, optionName)
+			}
+		}
+		return result
+	default:
+		positionals := make(xstrings.ArgumentStructFields, 0, len(fields))
+		for _, field := range fields {
+			if !field.Option {
+				positionals = append(positionals, field)
+			}
+		}
+		idx := 1
+		for idx < len(positionals) {
+			field := positionals[idx]
+			if positional <= 0 || field.Variadic {
+				break
+			}
+			positional -= field.MinArgCount
+			if positional < 0 {
+				break
+			}
+			idx++
+		}
+		if idx >= len(positionals) {
+			return result
+		}
+		fieldName = positionals[idx].Name
+	}
+
+	if f != nil {
+		for _, candidate := range f(leaf, fieldName, cur) {
+			if strings.HasPrefix(candidate, cur) {
+				result = append(result, candidate)
+			}
+		}
+	}
+	return result
+}
+
+func findField(fields xstrings.ArgumentStructFields, name string) *xstrings.ArgumentStructField {
+	for idx := range fields {
+		if fields[idx].Name == name {
+			return &fields[idx]
+		}
+	}
+	return nil
+}
+
+// isEndOfOptions reports whether args contain "--" which ends the options, instead of being an option value.
+func isEndOfOptions(h *Handler, fields xstrings.ArgumentStructFields, args ...string) bool {
+	for idx, arg := range args {
+		if arg != "--" {
+			continue
+		}
+		_, err := fields.SplitOptions(h.FieldNameFold, nil, args[:idx]...)
+		var e *xstrings.MissingArgumentError
+		if !errors.As(err, &e) {
+			return true
+		}
+	}
+	return false
+}
+
+func getOptionNames(fields xstrings.ArgumentStructFields) []string {
+	result := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !field.Option {
+			continue
+		}
+		if field.Long != "" {
+			result = append(result, "--"+field.Long)
+		}
+		if field.Short != 0 {
+			result = append(result, "-"+string(field.Short))
+		}
+	}
+	return result
+}
+
+// hasPrefix reports whether s begins with prefix. If fold is true, the runes are compared by case folding.
+func hasPrefix(s, prefix string, fold bool) bool {
+	if !fold {
+		return strings.HasPrefix(s, prefix)
+	}
+	for prefix != "" {
+		if s == "" {
+			return false
+		}
+		_, size := utf8.DecodeRuneInString(s)
+		_, prefixSize := utf8.DecodeRuneInString(prefix)
+		if !strings.EqualFold(s[:size], prefix[:prefixSize]) {
+			return false
+		}
+		s, prefix = s[size:], prefix[prefixSize:]
+	}
+	return true
+}
+
+func writeShellCases(b *strings.Builder, nodes []*completionNode) {
+	b.WriteString("\t\tcase \"$node/$word\" in\n")
+	for _, node := range nodes {
+		for _, edge := range node.edges {
+			fmt.Fprintf(b, "\t\t%d/%s) node=%d ;;\n", node.id, shellPattern(edge.name, edge.fold), edge.to)
+		}
+	}
+	b.WriteString("\t\t*) consumed=0; break ;;\n")
+	b.WriteString("\t\tesac\n")
+}
+
+func writeShellCandidates(b *strings.Builder, nodes []*completionNode) {
+	b.WriteString("\tif ((consumed)); then\n")
+	b.WriteString("\t\tcase \"$node\" in\n")
+	for _, node := range nodes {
+		if len(node.names) > 0 {
+			fmt.Fprintf(b, "\t\t%d) candidates+=(%s) ;;\n", node.id, joinQuoted(node.names, shellQuote))
+		}
+	}
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n")
+	b.WriteString("\t\tcase \"$node\" in\n")
+	for _, node := range nodes {
+		if len(node.options) > 0 {
+			fmt.Fprintf(b, "\t\t%d) candidates+=(%s) ;;\n", node.id, joinQuoted(node.options, shellQuote))
+		}
+	}
+	b.WriteString("\t\tesac\n")
+	b.WriteString("\tfi\n")
+}
+
+// writeFishCases writes the switch which sets next to the node of the word.
+// The names of the commands which fold case are compared in lower case.
+func writeFishCases(b *strings.Builder, nodes []*completionNode, fold bool, indent string) {
+	subject := "$word"
+	if fold {
+		subject = "(string lower -- $word)"
+	}
+	fmt.Fprintf(b, "%sswitch \"$node/\"%s\n", indent, subject)
+	for _, node := range nodes {
+		for _, edge := range node.edges {
+			if edge.fold != fold {
+				continue
+			}
+			name := edge.name
+			if fold {
+				name = strings.ToLower(name)
+			}
+			fmt.Fprintf(b, "%s\tcase %s\n", indent, fishQuote(fmt.Sprintf("%d/%s", node.id, name)))
+			fmt.Fprintf(b, "%s\t\tset next %d\n", indent, edge.to)
+		}
+	}
+	fmt.Fprintf(b, "%send\n", indent)
+}
+
+// shellPattern returns a case pattern of bash and zsh which matches name literally, or case insensitively if fold.
+func shellPattern(name string, fold bool) string {
+	b := &strings.Builder{}
+	for _, r := range name {
+		lower, upper := unicode.ToLower(r), unicode.ToUpper(r)
+		switch {
+		case fold && lower != upper:
+			fmt.Fprintf(b, "[%c%c]", lower, upper)
+		case r == '_' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func shellQuote(str string) string {
+	result, _ := (&xstrings.Arguments{Shell: true}).Format(str)
+	return result
+}
+
+func fishQuote(str string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(str) + "'"
+}
+
+func joinQuoted(strs []string, quote func(string) string) string {
+	result := make([]string, 0, len(strs))
+	for _, str := range strs {
+		result = append(result, quote(str))
+	}
+	return strings.Join(result, " ")
+}
@@ -0,0 +1,193 @@
+package command
+
+import (
+	"bytes"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type userAddArgs struct {
+	CmdName string
+	Role    string `option:"role,r"`
+	Force   bool   `option:"force,f"`
+	Name    string
+	Groups  []string
+}
+
+type serveArgs struct {
+	CmdName string
+	Port    int `option:"port,p"`
+	Dir     string
+}
+
+func newTestCompletion() *Completion {
+	return &Completion{
+		ProgName: "my-prog",
+		Commands: []Command{
+			NewGroup(NewNames(false, "user"),
+				NewWithRunFunc(&userAddArgs{}, nil, 0, 0, 0, false, "add"),
+				newTestCmd("del", "rm"),
+			),
+			NewWithRunFunc(&serveArgs{}, nil, 0, 0, 0, true, "Serve"),
+		},
+		FuncCompleteValue: func(cmd Command, fieldName string, prefix string) []string {
+			switch fieldName {
+			case "Role":
+				return []string{"admin", "user"}
+			case "Name":
+				return []string{"alice", "bob"}
+			case "Groups":
+				return []string{"wheel", "staff"}
+			case "Dir":
+				return []string{"/tmp", "/var"}
+			}
+			return nil
+		},
+	}
+}
+
+func TestCompletionComplete(t *testing.T) {
+	c := newTestCompletion()
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"user", "Serve"}},
+		{[]string{""}, []string{"user", "Serve"}},
+		{[]string{"s"}, []string{"Serve"}},
+		{[]string{"user", ""}, []string{"add", "del", "rm"}},
+		{[]string{"user", "r"}, []string{"rm"}},
+		{[]string{"user", "x", ""}, []string{}},
+		{[]string{"user", "add", ""}, []string{"alice", "bob"}},
+		{[]string{"user", "add", "a"}, []string{"alice"}},
+		{[]string{"user", "add", "-"}, []string{"--role", "-r", "--force", "-f"}},
+		{[]string{"user", "add", "--r"}, []string{"--role"}},
+		{[]string{"user", "add", "--role", ""}, []string{"admin", "user"}},
+		{[]string{"user", "add", "-fr", "a"}, []string{"admin"}},
+		{[]string{"user", "add", "-f", ""}, []string{"alice", "bob"}},
+		{[]string{"user", "add", "--role=admin", ""}, []string{"alice", "bob"}},
+		{[]string{"user", "add", "alice", ""}, []string{"wheel", "staff"}},
+		{[]string{"user", "add", "alice", "wheel", "s"}, []string{"staff"}},
+		{[]string{"user", "add", "--", "-"}, []string{}},
+		{[]string{"user", "add", "--", "-x", ""}, []string{"wheel", "staff"}},
+		{[]string{"user", "add", "-5", ""}, []string{"wheel", "staff"}},
+		{[]string{"user", "add", "-fradmin", ""}, []string{"alice", "bob"}},
+		{[]string{"user", "add", "-r", "--", ""}, []string{"alice", "bob"}},
+		{[]string{"user", "add", "-r", "--", "--f"}, []string{"--force"}},
+		{[]string{"user", "add", "-x", ""}, []string{}},
+		{[]string{"SERVE", "-p", "80", ""}, []string{"/tmp", "/var"}},
+		{[]string{"serve", "/tmp", ""}, []string{}},
+	}
+	for _, c2 := range cases {
+		got := c.Complete(c2.args...)
+		if !reflect.DeepEqual(got, c2.want) {
+			t.Errorf("Complete(%q) = %q, want %q", c2.args, got, c2.want)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if ok, err := c.Handle(buf, CompleteCommandName, "user", ""); !ok || err != nil {
+		t.Errorf("Handle = %v, %v, want true, nil", ok, err)
+	}
+	if want := "add\ndel\nrm\n"; buf.String() != want {
+		t.Errorf("Handle output = %q, want %q", buf.String(), want)
+	}
+	if ok, _ := c.Handle(buf, "user", "add"); ok {
+		t.Errorf("Handle of non-completion request = true")
+	}
+}
+
+func TestHasPrefix(t *testing.T) {
+	cases := []struct {
+		s, prefix string
+		fold      bool
+		want      bool
+	}{
+		{"serve", "se", false, true},
+		{"Serve", "se", false, false},
+		{"Serve", "se", true, true},
+		{"Ärger", "är", true, true},
+		{"ſerve", "se", true, true},
+		{"serve", "ſe", true, true},
+		{"ſ", "s", false, false},
+		{"se", "serve", true, false},
+		{"serve", "", true, true},
+	}
+	for _, c := range cases {
+		if got := hasPrefix(c.s, c.prefix, c.fold); got != c.want {
+			t.Errorf("hasPrefix(%q, %q, %v) = %v, want %v", c.s, c.prefix, c.fold, got, c.want)
+		}
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	c := newTestCompletion()
+	cases := []struct {
+		name  string
+		write func(c *Completion, buf *bytes.Buffer) error
+		check []string
+	}{
+		{"bash", func(c *Completion, buf *bytes.Buffer) error { return c.Bash(buf) }, []string{"bash", "-n"}},
+		{"zsh", func(c *Completion, buf *bytes.Buffer) error { return c.Zsh(buf) }, []string{"zsh", "-n"}},
+		{"fish", func(c *Completion, buf *bytes.Buffer) error { return c.Fish(buf) }, []string{"fish", "--no-execute"}},
+	}
+	for _, c2 := range cases {
+		buf := &bytes.Buffer{}
+		if err := c2.write(c, buf); err != nil {
+			t.Errorf("%s error: %v", c2.name, err)
+			continue
+		}
+
+		checkGolden(t, "completion."+c2.name, buf.Bytes())
+
+		if _, err := exec.LookPath(c2.check[0]); err != nil {
+			t.Logf("%s syntax check skipped: %v", c2.name, err)
+			continue
+		}
+		cmd := exec.Command(c2.check[0], c2.check[1:]...)
+		cmd.Stdin = strings.NewReader(buf.String())
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("%s syntax check error: %v\n%s", c2.name, err, out)
+		}
+	}
+}
+
+func TestCompletionBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := newTestCompletion().Bash(buf); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"my-prog ", "user Serve"},
+		{"my-prog user ", "add del rm"},
+		{"my-prog USER ", ""},
+		{"my-prog sERVE -", "--port -p"},
+		{"my-prog user add --f", "--force"},
+	}
+	for _, c := range cases {
+		script := buf.String() + `
+COMP_WORDS=(` + c.line + `"")
+COMP_CWORD=$((${#COMP_WORDS[@]} - 1))
+COMP_WORDS[0]=false
+_my_prog
+echo "${COMPREPLY[*]}"
+`
+		cmd := exec.Command("bash", "-c", script)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("%q error: %v\n%s", c.line, err, out)
+			continue
+		}
+		if got := strings.TrimSuffix(string(out), "\n"); got != c.want {
+			t.Errorf("%q completes %q, want %q", c.line, got, c.want)
+		}
+	}
+}
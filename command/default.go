@@ -0,0 +1,7 @@
+package command
+
+var (
+	DefaultSuggestionDistance = 2
+
+	DefaultHelpWidth = 80
+)
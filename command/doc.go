@@ -0,0 +1,289 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DocGenerator generates reference documents of commands as roff man pages or Markdown.
+type DocGenerator struct {
+	Handler  *Handler
+	ProgName string
+
+	// Section is the man page section. If Section is empty, "1" is used.
+	Section string
+}
+
+// WalkCommands calls f for each of cmds and their sub commands recursively, in depth-first order.
+// cmdPath is prefix and the primary names of the commands from the top level command, separated by space.
+func WalkCommands(cmds []Command, prefix string, f func(cmdPath string, cmd Command) error) error {
+	for _, cmd := range cmds {
+		cmdNames := cmd.CmdNames()
+		if len(cmdNames) <= 0 {
+			continue
+		}
+		cmdPath := strings.TrimSpace(prefix + " " + cmdNames[0])
+		if err := f(cmdPath, cmd); err != nil {
+			return err
+		}
+		if p, ok := cmd.(Parent); ok {
+			if err := WalkCommands(p.SubCommands(), cmdPath, f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ManPage writes the man page of cmd to w. cmdPath is the command line which runs cmd, e.g. "prog user add".
+func (d *DocGenerator) ManPage(w io.Writer, cmdPath string, cmd Command) error {
+	doc, err := d.newCommandDoc(cmdPath, cmd)
+	if err != nil {
+		return err
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, ".TH %s %s\n", roffQuote(strings.ToUpper(strings.Replace(cmdPath, " ", "-", -1))), roffQuote(d.getSection()))
+	b.WriteString(".SH NAME\n")
+	b.WriteString(roffEscape(cmdPath))
+	if doc.help.Short != "" {
+		b.WriteString(` \- ` + roffEscape(doc.help.Short))
+	}
+	b.WriteString("\n")
+	d.writeManBody(b, doc, false)
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// CombinedManPage writes the man page of ProgName which documents cmds and their sub commands to w.
+func (d *DocGenerator) CombinedManPage(w io.Writer, cmds []Command) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, ".TH %s %s\n", roffQuote(strings.ToUpper(d.ProgName)), roffQuote(d.getSection()))
+	b.WriteString(".SH NAME\n")
+	b.WriteString(roffEscape(d.ProgName) + "\n")
+	b.WriteString(".SH SYNOPSIS\n")
+	b.WriteString(`\fB` + roffEscape(d.ProgName) + `\fR <command> [<args>...]` + "\n")
+	b.WriteString(".SH COMMANDS\n")
+	err := WalkCommands(cmds, d.ProgName, func(cmdPath string, cmd Command) error {
+		doc, err := d.newCommandDoc(cmdPath, cmd)
+		if err != nil {
+			return err
+		}
+		b.WriteString(".SS " + roffQuote(cmdPath) + "\n")
+		if doc.help.Short != "" {
+			b.WriteString(roffEscape(doc.help.Short) + "\n")
+		}
+		d.writeManBody(b, doc, true)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writeManBody writes the sections of doc. If nested, the sections are written as indented blocks in a sub section.
+func (d *DocGenerator) writeManBody(b *strings.Builder, doc *commandDoc, nested bool) {
+	heading := func(title string) {
+		if nested {
+			b.WriteString(".PP\n.I " + roffQuote(title) + "\n.RS\n")
+			return
+		}
+		b.WriteString(".SH " + strings.ToUpper(title) + "\n")
+	}
+	end := func() {
+		if nested {
+			b.WriteString(".RE\n")
+		}
+	}
+
+	heading("Synopsis")
+	for _, synopsis := range doc.synopses {
+		b.WriteString(".PP\n")
+		b.WriteString(`\fB` + roffEscape(doc.cmdPath) + `\fR` + roffEscape(strings.TrimPrefix(synopsis, doc.cmdPath)) + "\n")
+	}
+	end()
+
+	if paragraphs := splitParagraphs(doc.help.Long); len(paragraphs) > 0 {
+		heading("Description")
+		for _, paragraph := range paragraphs {
+			b.WriteString(".PP\n" + roffEscape(strings.Join(strings.Fields(paragraph), " ")) + "\n")
+		}
+		end()
+	}
+
+	if len(doc.cmdNames) > 1 {
+		heading("Aliases")
+		b.WriteString(roffEscape(strings.Join(doc.cmdNames, ", ")) + "\n")
+		end()
+	}
+
+	writeItems := func(title string, items [][2]string) {
+		if len(items) <= 0 {
+			return
+		}
+		heading(title)
+		for _, item := range items {
+			b.WriteString(".TP\n" + `\fB` + roffEscape(item[0]) + `\fR` + "\n")
+			for idx, paragraph := range splitParagraphs(item[1]) {
+				if idx > 0 {
+					b.WriteString(".IP\n")
+				}
+				b.WriteString(roffEscape(strings.Join(strings.Fields(paragraph), " ")) + "\n")
+			}
+		}
+		end()
+	}
+	writeItems("Arguments", doc.arguments)
+	writeItems("Options", doc.options)
+	writeItems("Commands", doc.subCmds)
+
+	if len(doc.help.Examples) > 0 {
+		heading("Examples")
+		for _, example := range doc.help.Examples {
+			b.WriteString(".PP\n.nf\n.RS\n")
+			for _, line := range strings.Split(example, "\n") {
+				b.WriteString(roffEscape(line) + "\n")
+			}
+			b.WriteString(".RE\n.fi\n")
+		}
+		end()
+	}
+}
+
+// Markdown writes the Markdown reference document of cmds and their sub commands to w.
+func (d *DocGenerator) Markdown(w io.Writer, cmds []Command) error {
+	b := &strings.Builder{}
+	if d.ProgName != "" {
+		b.WriteString("# " + markdownEscape(d.ProgName) + "\n")
+	}
+	err := WalkCommands(cmds, d.ProgName, func(cmdPath string, cmd Command) error {
+		doc, err := d.newCommandDoc(cmdPath, cmd)
+		if err != nil {
+			return err
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("## " + markdownEscape(cmdPath) + "\n")
+		if doc.help.Short != "" {
+			b.WriteString("\n" + markdownEscape(doc.help.Short) + "\n")
+		}
+		b.WriteString("\n```\n" + strings.Join(doc.synopses, "\n") + "\n```\n")
+		for _, paragraph := range splitParagraphs(doc.help.Long) {
+			b.WriteString("\n" + markdownEscape(paragraph) + "\n")
+		}
+		if len(doc.cmdNames) > 1 {
+			quoted := make([]string, 0, len(doc.cmdNames))
+			for _, cmdName := range doc.cmdNames {
+				quoted = append(quoted, "`"+cmdName+"`")
+			}
+			b.WriteString("\nAliases: " + strings.Join(quoted, ", ") + "\n")
+		}
+		writeTable := func(title, header string, items [][2]string) {
+			if len(items) <= 0 {
+				return
+			}
+			b.WriteString("\n### " + title + "\n\n")
+			b.WriteString("| " + header + " | Description |\n| --- | --- |\n")
+			for _, item := range items {
+				b.WriteString("| `" + item[0] + "` | " + markdownCell(item[1]) + " |\n")
+			}
+		}
+		writeTable("Arguments", "Argument", doc.arguments)
+		writeTable("Options", "Option", doc.options)
+		writeTable("Commands", "Command", doc.subCmds)
+		if len(doc.help.Examples) > 0 {
+			b.WriteString("\n### Examples\n\n```\n" + strings.Join(doc.help.Examples, "\n") + "\n```\n")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func (d *DocGenerator) getHandler() *Handler {
+	if d.Handler == nil {
+		return &Handler{}
+	}
+	return d.Handler
+}
+
+func (d *DocGenerator) getSection() string {
+	if d.Section == "" {
+		return "1"
+	}
+	return d.Section
+}
+
+type commandDoc struct {
+	cmdPath   string
+	cmdNames  []string
+	help      Help
+	synopses  []string
+	arguments [][2]string
+	options   [][2]string
+	subCmds   [][2]string
+}
+
+func (d *DocGenerator) newCommandDoc(cmdPath string, cmd Command) (*commandDoc, error) {
+	fields, err := d.getHandler().parameterFields(cmd)
+	if err != nil {
+		return nil, err
+	}
+	doc := &commandDoc{
+		cmdPath:  cmdPath,
+		cmdNames: cmd.CmdNames(),
+		help:     GetHelp(cmd),
+		synopses: getSynopses(cmdPath, cmd, fields),
+	}
+	for _, field := range fields {
+		if field.Option {
+			doc.options = append(doc.options, [2]string{strings.TrimSpace(optionUsage(field)), fieldDescription(field)})
+			continue
+		}
+		doc.arguments = append(doc.arguments, [2]string{fieldUsage(field), fieldDescription(field)})
+	}
+	if p, ok := cmd.(Parent); ok {
+		for _, subCmd := range p.SubCommands() {
+			doc.subCmds = append(doc.subCmds, [2]string{strings.Join(subCmd.CmdNames(), ", "), GetHelp(subCmd).Short})
+		}
+	}
+	return doc, nil
+}
+
+// roffEscape escapes str to be written as text in roff.
+func roffEscape(str string) string {
+	str = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(str)
+	if strings.HasPrefix(str, ".") || strings.HasPrefix(str, "'") {
+		str = `\&` + str
+	}
+	return str
+}
+
+// roffQuote quotes str to be written as a macro argument in roff.
+func roffQuote(str string) string {
+	return `"` + strings.Replace(roffEscape(str), `"`, `\(dq`, -1) + `"`
+}
+
+func markdownEscape(str string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "<", `\<`, "[", `\[`, "]", `\]`).Replace(str)
+}
+
+// markdownCell formats str as a table cell. The paragraphs of str are separated by line breaks,
+// because a table cell can't contain new lines.
+func markdownCell(str string) string {
+	paragraphs := strings.Split(str, "\n\n")
+	for idx, paragraph := range paragraphs {
+		paragraphs[idx] = strings.Replace(markdownEscape(strings.Join(strings.Fields(paragraph), " ")), "|", `\|`, -1)
+	}
+	return strings.Join(paragraphs, "<br><br>")
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares got with the golden file testdata/name, and updates the golden file if the update flag is set.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n%s", golden, got)
+	}
+}
+
+func newTestDocCommands() []Command {
+	return []Command{
+		NewGroup(WithHelp(NewNames(false, "user"), Help{Short: "Manage users."}),
+			NewWithRunFunc(&helpArgs{}, nil, 0, 0, 0, false, "add", "new"),
+		),
+	}
+}
+
+func TestDocGeneratorManPage(t *testing.T) {
+	d := &DocGenerator{ProgName: "prog"}
+	cmd := newTestDocCommands()[0].(Parent).SubCommands()[0]
+	buf := &bytes.Buffer{}
+	if err := d.ManPage(buf, "prog user add", cmd); err != nil {
+		t.Fatalf("ManPage error: %v", err)
+	}
+	checkGolden(t, "prog-user-add.1", buf.Bytes())
+}
+
+func TestDocGeneratorCombinedManPage(t *testing.T) {
+	d := &DocGenerator{ProgName: "prog", Section: "8"}
+	buf := &bytes.Buffer{}
+	if err := d.CombinedManPage(buf, newTestDocCommands()); err != nil {
+		t.Fatalf("CombinedManPage error: %v", err)
+	}
+	checkGolden(t, "prog.8", buf.Bytes())
+}
+
+func TestDocGeneratorMarkdown(t *testing.T) {
+	d := &DocGenerator{ProgName: "prog"}
+	buf := &bytes.Buffer{}
+	if err := d.Markdown(buf, newTestDocCommands()); err != nil {
+		t.Fatalf("Markdown error: %v", err)
+	}
+	checkGolden(t, "prog.md", buf.Bytes())
+}
+
+func TestRoffEscape(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"a-b", `a\-b`},
+		{`a\b`, `a\eb`},
+		{".TH", `\&.TH`},
+		{"'a", `\&'a`},
+	}
+	for _, c := range cases {
+		if got := roffEscape(c.in); got != c.want {
+			t.Errorf("roffEscape(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+	if got, want := roffQuote(`a "b"`), `"a \(dqb\(dq"`; got != want {
+		t.Errorf("roffQuote = %q, want %q", got, want)
+	}
+	if got, want := markdownCell("a|b\n*c*"), `a\|b \*c\*`; got != want {
+		t.Errorf("markdownCell = %q, want %q", got, want)
+	}
+}
@@ -3,15 +3,18 @@ package command
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
-	ErrCommandNotSet = errors.New("command not set")
+	ErrCommandNotSet    = errors.New("command not set")
+	ErrSubCommandNotSet = errors.New("sub command not set")
 )
 
 type UnknownCommandError struct {
-	name string
-	err  error
+	name        string
+	err         error
+	suggestions []string
 }
 
 func (e *UnknownCommandError) Error() string {
@@ -19,6 +22,17 @@ func (e *UnknownCommandError) Error() string {
 	if e.name != "" {
 		str = fmt.Sprintf("%s %q", str, e.name)
 	}
+	if l := len(e.suggestions); l > 0 {
+		quoted := make([]string, 0, l)
+		for _, suggestion := range e.suggestions {
+			quoted = append(quoted, fmt.Sprintf("%q", suggestion))
+		}
+		alts := quoted[l-1]
+		if l > 1 {
+			alts = strings.Join(quoted[:l-1], ", ") + " or " + alts
+		}
+		str = fmt.Sprintf("%s, did you mean %s?", str, alts)
+	}
 	if e.err == nil || e.err.Error() == "" {
 		return str
 	}
@@ -32,3 +46,125 @@ func (e *UnknownCommandError) Unwrap() error {
 func (e *UnknownCommandError) Name() string {
 	return e.name
 }
+
+// Suggestions returns the command names which are close to the unknown command name, the closest first.
+func (e *UnknownCommandError) Suggestions() []string {
+	result := make([]string, len(e.suggestions))
+	copy(result, e.suggestions)
+	return result
+}
+
+// AmbiguousCommandError is returned when a command name prefix matches more than one command.
+type AmbiguousCommandError struct {
+	name       string
+	err        error
+	candidates []string
+}
+
+func (e *AmbiguousCommandError) Error() string {
+	str := "ambiguous command"
+	if e.name != "" {
+		str = fmt.Sprintf("%s %q", str, e.name)
+	}
+	if l := len(e.candidates); l > 0 {
+		quoted := make([]string, 0, l)
+		for _, candidate := range e.candidates {
+			quoted = append(quoted, fmt.Sprintf("%q", candidate))
+		}
+		str = fmt.Sprintf("%s, candidates are %s", str, strings.Join(quoted, ", "))
+	}
+	if e.err == nil || e.err.Error() == "" {
+		return str
+	}
+	return fmt.Sprintf("%s: %v", str, e.err)
+}
+
+func (e *AmbiguousCommandError) Unwrap() error {
+	return e.err
+}
+
+func (e *AmbiguousCommandError) Name() string {
+	return e.name
+}
+
+// Candidates returns the command names which the ambiguous command name is a prefix of.
+func (e *AmbiguousCommandError) Candidates() []string {
+	result := make([]string, len(e.candidates))
+	copy(result, e.candidates)
+	return result
+}
+
+type DuplicateCommandError struct {
+	name string
+	err  error
+}
+
+func (e *DuplicateCommandError) Error() string {
+	str := "duplicate command"
+	if e.name != "" {
+		str = fmt.Sprintf("%s %q", str, e.name)
+	}
+	if e.err == nil || e.err.Error() == "" {
+		return str
+	}
+	return fmt.Sprintf("%s: %v", str, e.err)
+}
+
+func (e *DuplicateCommandError) Unwrap() error {
+	return e.err
+}
+
+func (e *DuplicateCommandError) Name() string {
+	return e.name
+}
+
+// ArgumentError wraps the error which occurs while unmarshaling arguments into a command.
+type ArgumentError struct {
+	name string
+	err  error
+}
+
+func (e *ArgumentError) Error() string {
+	str := "command"
+	if e.name != "" {
+		str = fmt.Sprintf("%s %q", str, e.name)
+	}
+	str = fmt.Sprintf("%s argument error", str)
+	if e.err == nil || e.err.Error() == "" {
+		return str
+	}
+	return fmt.Sprintf("%s: %v", str, e.err)
+}
+
+func (e *ArgumentError) Unwrap() error {
+	return e.err
+}
+
+func (e *ArgumentError) Name() string {
+	return e.name
+}
+
+// PanicError is returned by Recover when a command panics.
+type PanicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+func (e *PanicError) Unwrap() error {
+	err, _ := e.value.(error)
+	return err
+}
+
+// Value returns the value which is given to panic.
+func (e *PanicError) Value() interface{} {
+	return e.value
+}
+
+// Stack returns the stack trace of the goroutine at the time of recovery.
+func (e *PanicError) Stack() []byte {
+	return e.stack
+}
@@ -8,9 +8,26 @@ import (
 
 type Handler struct {
 	Unmarshaler              *xstrings.Unmarshaler
+	Marshaler                *xstrings.Marshaler
 	FieldNameBeginsLowerCase bool
 	FieldNameFold            bool
 	FieldTagKey              string
+
+	// ValidateTagKey is the struct tag key of the validation constraints of the command fields.
+	// See xstrings.ArgumentStruct.ValidateTagKey. If ValidateTagKey is empty, validation is disabled.
+	ValidateTagKey string
+
+	// CodecTagKey is the struct tag key of the codec options of the command fields.
+	// See xstrings.ArgumentStruct.CodecTagKey. If CodecTagKey is empty, xstrings.DefaultCodecTagKey is used.
+	CodecTagKey string
+
+	// SuggestionDistance is the maximum edit distance of the command names which UnknownCommandError suggests.
+	// Zero means DefaultSuggestionDistance, and negative disables suggestions.
+	SuggestionDistance int
+
+	// PrefixMatch enables selecting a command by an unambiguous prefix of its names.
+	// An ambiguous prefix causes AmbiguousCommandError.
+	PrefixMatch bool
 }
 
 func (h *Handler) Unmarshal(cmd Command, args ...string) error {
@@ -30,7 +47,29 @@ func (h *Handler) Find(cmds []Command, args ...string) (int, error) {
 			return idx, nil
 		}
 	}
-	return -1, &UnknownCommandError{cmdName, nil}
+	if h.PrefixMatch {
+		found := -1
+		candidates := make([]string, 0, len(cmds))
+		for idx, cmd := range cmds {
+			for _, cmdName2 := range cmd.CmdNames() {
+				if hasPrefix(cmdName2, cmdName, cmd.CmdNamesFold()) {
+					if found < 0 || found == idx {
+						found = idx
+					} else {
+						found = len(cmds)
+					}
+					candidates = append(candidates, cmdName2)
+				}
+			}
+		}
+		if found >= len(cmds) {
+			return -1, &AmbiguousCommandError{cmdName, nil, candidates}
+		}
+		if found >= 0 {
+			return found, nil
+		}
+	}
+	return -1, &UnknownCommandError{cmdName, nil, suggestCmdNames(cmds, cmdName, h.getSuggestionDistance())}
 }
 
 func (h *Handler) FindCmd(cmds []Command, args ...string) (Command, error) {
@@ -41,12 +80,41 @@ func (h *Handler) FindCmd(cmds []Command, args ...string) (Command, error) {
 	return cmds[idx], nil
 }
 
-func (h *Handler) FindAndUnmarshal(cmds []Command, args ...string) (Command, error) {
+// FindPath finds the command by args[0], and walks into its sub commands as deep as args match.
+// It returns the commands from the top level command to the leaf command.
+// The arguments of the leaf command are args[len(path)-1:]. AmbiguousCommandError is returned at any depth.
+func (h *Handler) FindPath(cmds []Command, args ...string) ([]Command, error) {
 	cmd, err := h.FindCmd(cmds, args...)
 	if err != nil {
 		return nil, err
 	}
-	return cmd, h.Unmarshal(cmd, args...)
+	path := []Command{cmd}
+	for depth := 1; depth < len(args); depth++ {
+		p, ok := cmd.(Parent)
+		if !ok {
+			break
+		}
+		subCmd, err := h.FindCmd(p.SubCommands(), args[depth:]...)
+		if err != nil {
+			if _, ok := err.(*AmbiguousCommandError); ok || isGroup(cmd) {
+				return nil, err
+			}
+			break
+		}
+		path = append(path, subCmd)
+		cmd = subCmd
+	}
+	return path, nil
+}
+
+// FindAndUnmarshal finds the leaf command by FindPath, and unmarshals the arguments of the leaf command into it.
+func (h *Handler) FindAndUnmarshal(cmds []Command, args ...string) (Command, error) {
+	path, err := h.FindPath(cmds, args...)
+	if err != nil {
+		return nil, err
+	}
+	cmd := path[len(path)-1]
+	return cmd, h.Unmarshal(cmd, args[len(path)-1:]...)
 }
 
 func (h *Handler) Usage(cmd Command, cmdName string, prefix string) (string, error) {
@@ -55,7 +123,7 @@ func (h *Handler) Usage(cmd Command, cmdName string, prefix string) (string, err
 		return "", err
 	}
 	if cmdName != "" && !cmd.Is(cmdName) {
-		return "", &UnknownCommandError{cmdName, nil}
+		return "", &UnknownCommandError{cmdName, nil, nil}
 	}
 	result := ""
 	for idx, cmdName2 := range cmd.CmdNames() {
@@ -71,25 +139,53 @@ func (h *Handler) Usage(cmd Command, cmdName string, prefix string) (string, err
 			}
 		}
 		nl := ""
-		if idx > 0 {
+		if idx > 0 && result != "" {
 			nl = "\n"
 		}
-		result += nl + prefix + cmdName2 + " " + usage
+		if !isGroup(cmd) {
+			result += nl + prefix + cmdName2 + " " + usage
+			nl = "\n"
+		}
+		if p, ok := cmd.(Parent); ok {
+			for _, subCmd := range p.SubCommands() {
+				subUsage, err := h.Usage(subCmd, "", prefix+cmdName2+" ")
+				if err != nil {
+					return "", err
+				}
+				if subUsage == "" {
+					continue
+				}
+				result += nl + subUsage
+				nl = "\n"
+			}
+		}
 	}
 	return result, nil
 }
 
 func (h *Handler) ParameterUsage(cmd Command) (string, error) {
-	fields, err := h.getArgumentStruct(cmd).Fields(cmd)
+	fields, err := h.parameterFields(cmd)
 	if err != nil {
 		return "", err
 	}
-	if len(fields) > 0 {
-		fields = fields[1:]
-	}
 	return fields.String(), nil
 }
 
+// parameterFields returns the fields of cmd without the field which is bound to the command name.
+func (h *Handler) parameterFields(cmd Command) (xstrings.ArgumentStructFields, error) {
+	fields, err := h.getArgumentStruct(cmd).Fields(cmd)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range fields {
+		if !fields[idx].Option {
+			fields = append(fields[:idx:idx], fields[idx+1:]...)
+			break
+		}
+	}
+	return fields, nil
+}
+
 func (h *Handler) checkArgs(args ...string) error {
 	sizeArgs := len(args)
 	if sizeArgs <= 0 {
@@ -101,12 +197,22 @@ func (h *Handler) checkArgs(args ...string) error {
 	return nil
 }
 
+func (h *Handler) getSuggestionDistance() int {
+	if h.SuggestionDistance == 0 {
+		return DefaultSuggestionDistance
+	}
+	return h.SuggestionDistance
+}
+
 func (h *Handler) getArgumentStruct(cmd Command) *xstrings.ArgumentStruct {
 	return &xstrings.ArgumentStruct{
 		Unmarshaler:              h.Unmarshaler,
+		Marshaler:                h.Marshaler,
 		FieldNameBeginsLowerCase: h.FieldNameBeginsLowerCase,
 		FieldNameFold:            h.FieldNameFold,
 		FieldTagKey:              h.FieldTagKey,
+		ValidateTagKey:           h.ValidateTagKey,
+		CodecTagKey:              h.CodecTagKey,
 		FieldOffset:              cmd.FieldOffset(),
 		ArgCountMin:              cmd.ArgCountMin(),
 		ArgCountMax:              cmd.ArgCountMax(),
@@ -0,0 +1,194 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/goinsane/xstrings"
+)
+
+type testArgs struct {
+	CmdName string
+	Args    []string
+}
+
+func newTestCmd(names ...string) Command {
+	return NewWithRunFunc(&testArgs{}, func(ctx context.Context) error {
+		return nil
+	}, 0, 0, 0, false, names...)
+}
+
+func getTestArgs(cmd Command) *testArgs {
+	var result *testArgs
+	lookupCommand(cmd, func(ifc interface{}) bool {
+		result, _ = ifc.(*testArgs)
+		return result != nil
+	})
+	return result
+}
+
+func getCmdPath(path []Command) []string {
+	result := make([]string, 0, len(path))
+	for _, cmd := range path {
+		result = append(result, cmd.CmdNames()[0])
+	}
+	return result
+}
+
+func TestHandlerFind(t *testing.T) {
+	cmds := []Command{
+		newTestCmd("show", "sh"),
+		newTestCmd("shutdown"),
+		NewWithRunFunc(&testArgs{}, nil, 0, 0, 0, true, "Start"),
+	}
+	cases := []struct {
+		prefixMatch bool
+		cmdName     string
+		want        int
+		wantErr     interface{}
+	}{
+		{false, "show", 0, nil},
+		{false, "sh", 0, nil},
+		{false, "start", 2, nil},
+		{false, "shut", -1, &UnknownCommandError{}},
+		{true, "shut", 1, nil},
+		{true, "sho", 0, nil},
+		{true, "sh", 0, nil},
+		{true, "st", 2, nil},
+		{true, "s", -1, &AmbiguousCommandError{}},
+		{true, "x", -1, &UnknownCommandError{}},
+	}
+	for _, c := range cases {
+		h := &Handler{PrefixMatch: c.prefixMatch}
+		idx, err := h.Find(cmds, c.cmdName)
+		if idx != c.want || reflect.TypeOf(err) != reflect.TypeOf(c.wantErr) {
+			t.Errorf("PrefixMatch %v: Find(%q) = %d, %v, want %d, %T", c.prefixMatch, c.cmdName, idx, err, c.want, c.wantErr)
+		}
+	}
+
+	_, err := (&Handler{PrefixMatch: true}).Find(cmds, "s")
+	if e, ok := err.(*AmbiguousCommandError); !ok || !reflect.DeepEqual(e.Candidates(), []string{"show", "sh", "shutdown", "Start"}) {
+		t.Errorf("Find(%q) error = %v", "s", err)
+	}
+	if _, err := (&Handler{}).Find(cmds); err != ErrCommandNotSet {
+		t.Errorf("Find() error = %v, want %v", err, ErrCommandNotSet)
+	}
+}
+
+func TestHandlerFindPath(t *testing.T) {
+	cmds := []Command{
+		NewGroup(NewNames(false, "user"),
+			newTestCmd("add"),
+			newTestCmd("admin"),
+		),
+		WithSubCommands(NewWithRunFunc(&testArgs{}, nil, 0, 0, 0, false, "serve"),
+			newTestCmd("status"),
+			newTestCmd("stop"),
+		),
+	}
+	cases := []struct {
+		args    []string
+		want    []string
+		wantErr interface{}
+	}{
+		{[]string{"user", "add", "x"}, []string{"user", "add"}, nil},
+		{[]string{"user", "adm"}, []string{"user", "admin"}, nil},
+		{[]string{"user"}, []string{"user"}, nil},
+		{[]string{"user", "del"}, nil, &UnknownCommandError{}},
+		{[]string{"user", "ad"}, nil, &AmbiguousCommandError{}},
+		{[]string{"serve", "stop"}, []string{"serve", "stop"}, nil},
+		{[]string{"serve", "x"}, []string{"serve"}, nil},
+		{[]string{"serve", "st"}, nil, &AmbiguousCommandError{}},
+	}
+	h := &Handler{PrefixMatch: true}
+	for _, c := range cases {
+		path, err := h.FindPath(cmds, c.args...)
+		if reflect.TypeOf(err) != reflect.TypeOf(c.wantErr) {
+			t.Errorf("FindPath(%q) error = %v, want %T", c.args, err, c.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := getCmdPath(path); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("FindPath(%q) = %q, want %q", c.args, got, c.want)
+		}
+	}
+}
+
+func TestHandlerFindAndUnmarshal(t *testing.T) {
+	cmds := []Command{
+		NewGroup(NewNames(false, "user"),
+			newTestCmd("add"),
+		),
+	}
+	cmd, err := (&Handler{}).FindAndUnmarshal(cmds, "user", "add", "a", "b")
+	if err != nil {
+		t.Fatalf("FindAndUnmarshal error: %v", err)
+	}
+	want := &testArgs{CmdName: "add", Args: []string{"a", "b"}}
+	if got := getTestArgs(cmd); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindAndUnmarshal = %+v, want %+v", got, want)
+	}
+}
+
+func TestHandlerUsage(t *testing.T) {
+	type userArgs struct {
+		CmdName string
+		Name    string
+	}
+	cmd := NewGroup(NewNames(false, "user", "u"),
+		NewWithRunFunc(&userArgs{}, nil, 0, 0, 0, false, "add"),
+		WithSubCommands(NewWithRunFunc(&userArgs{}, nil, 0, 0, 0, false, "list"),
+			NewWithRunFunc(&userArgs{}, nil, 0, 0, 0, false, "all"),
+		),
+	)
+	usage, err := (&Handler{}).Usage(cmd, "user", "prog ")
+	if err != nil {
+		t.Fatalf("Usage error: %v", err)
+	}
+	want := "prog user add [<Name>]\nprog user list [<Name>]\nprog user list all [<Name>]"
+	if usage != want {
+		t.Errorf("Usage = %q, want %q", usage, want)
+	}
+}
+
+func TestHandlerValidation(t *testing.T) {
+	type addArgs struct {
+		CmdName string
+		Name    string `check:"nonempty"`
+	}
+	newRegistry := func(h *Handler) *Registry {
+		r := &Registry{Handler: h}
+		r.MustRegister(NewWithRunFunc(&addArgs{}, func(ctx context.Context) error {
+			return nil
+		}, 0, 0, 0, false, "add"))
+		return r
+	}
+
+	err := newRegistry(&Handler{ValidateTagKey: "check"}).Execute(context.Background(), "add")
+	var e *xstrings.ArgumentValidationError
+	if !errors.As(err, &e) || e.Name() != "Name" {
+		t.Errorf("Execute error = %v, want *xstrings.ArgumentValidationError of Name", err)
+	}
+	if err := newRegistry(&Handler{}).Execute(context.Background(), "add"); err != nil {
+		t.Errorf("Execute without ValidateTagKey error: %v", err)
+	}
+}
+
+func TestHandlerCodecTagKey(t *testing.T) {
+	type setArgs struct {
+		CmdName string
+		Mask    uint `codec:"base=16"`
+	}
+	var args setArgs
+	cmds := []Command{NewWithRunFunc(&args, nil, 0, 0, 0, false, "set")}
+	if _, err := (&Handler{CodecTagKey: "codec"}).FindAndUnmarshal(cmds, "set", "ff"); err != nil || args.Mask != 0xff {
+		t.Errorf("FindAndUnmarshal = %d, %v, want 255", args.Mask, err)
+	}
+	if _, err := (&Handler{}).FindAndUnmarshal(cmds, "set", "ff"); err == nil {
+		t.Errorf("FindAndUnmarshal without CodecTagKey doesn't return error")
+	}
+}
@@ -0,0 +1,302 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goinsane/xstrings"
+)
+
+// Help is the help text of a command.
+type Help struct {
+	// Short is the one line summary of the command.
+	Short string
+
+	// Long is the description of the command. Paragraphs are separated by blank lines.
+	Long string
+
+	// Examples are the example command lines, which are rendered verbatim.
+	Examples []string
+}
+
+// Helper is the optional interface which is implemented by commands which have help text.
+// It is looked up on the command, its Names and the value which is given to NewRunnable.
+type Helper interface {
+	CmdHelp() Help
+}
+
+// WithHelp returns a new Names which behaves like names and has help, e.g. for NewGroup.
+func WithHelp(names Names, help Help) Names {
+	return &helpNamesStruct{
+		Names: names,
+		help:  help,
+	}
+}
+
+type helpNamesStruct struct {
+	Names
+	help Help
+}
+
+func (h *helpNamesStruct) CmdHelp() Help {
+	return h.help
+}
+
+// GetHelp returns the help text of cmd, or zero Help if cmd doesn't have help text.
+func GetHelp(cmd Command) Help {
+	var result Help
+	lookupCommand(cmd, func(ifc interface{}) bool {
+		if h, ok := ifc.(Helper); ok {
+			result = h.CmdHelp()
+			return true
+		}
+		return false
+	})
+	return result
+}
+
+// HelpRenderer renders the help pages of commands.
+type HelpRenderer struct {
+	Handler *Handler
+
+	// Width is the terminal width which the help page is wrapped to.
+	// If Width is zero, DefaultHelpWidth is used.
+	Width int
+}
+
+// Render writes the help page of cmd to w. cmdPath is the command line which runs cmd, e.g. "prog user add".
+func (r *HelpRenderer) Render(w io.Writer, cmdPath string, cmd Command) error {
+	h := r.getHandler()
+	width := r.Width
+	if width <= 0 {
+		width = DefaultHelpWidth
+	}
+
+	fields, err := h.parameterFields(cmd)
+	if err != nil {
+		return err
+	}
+	help := GetHelp(cmd)
+
+	b := &strings.Builder{}
+	section := func(title string) {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if title != "" {
+			b.WriteString(title + ":\n")
+		}
+	}
+
+	section("Usage")
+	for _, synopsis := range getSynopses(cmdPath, cmd, fields) {
+		b.WriteString(wrapText(synopsis, width, "  ", "      "))
+	}
+	var subCmds []Command
+	if p, ok := cmd.(Parent); ok {
+		subCmds = p.SubCommands()
+	}
+
+	if paragraphs := append(splitParagraphs(help.Short), splitParagraphs(help.Long)...); len(paragraphs) > 0 {
+		section("")
+		for idx, paragraph := range paragraphs {
+			if idx > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(wrapText(paragraph, width, "", ""))
+		}
+	}
+
+	if cmdNames := cmd.CmdNames(); len(cmdNames) > 1 {
+		section("Aliases")
+		b.WriteString(wrapText(strings.Join(cmdNames, ", "), width, "  ", "  "))
+	}
+
+	rows := make([][2]string, 0, len(fields))
+	for _, field := range fields {
+		if field.Option {
+			continue
+		}
+		rows = append(rows, [2]string{fieldUsage(field), fieldDescription(field)})
+	}
+	if len(rows) > 0 {
+		section("Arguments")
+		b.WriteString(renderTable(rows, width))
+	}
+
+	rows = rows[:0]
+	for _, field := range fields {
+		if !field.Option {
+			continue
+		}
+		rows = append(rows, [2]string{optionUsage(field), fieldDescription(field)})
+	}
+	if len(rows) > 0 {
+		section("Options")
+		b.WriteString(renderTable(rows, width))
+	}
+
+	rows = rows[:0]
+	for _, subCmd := range subCmds {
+		rows = append(rows, [2]string{strings.Join(subCmd.CmdNames(), ", "), GetHelp(subCmd).Short})
+	}
+	if len(rows) > 0 {
+		section("Commands")
+		b.WriteString(renderTable(rows, width))
+	}
+
+	if len(help.Examples) > 0 {
+		section("Examples")
+		for _, example := range help.Examples {
+			for _, line := range strings.Split(example, "\n") {
+				b.WriteString("  " + line + "\n")
+			}
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func (r *HelpRenderer) getHandler() *Handler {
+	if r.Handler == nil {
+		return &Handler{}
+	}
+	return r.Handler
+}
+
+// getSynopses returns the command lines which run cmd, or its sub commands.
+func getSynopses(cmdPath string, cmd Command, fields xstrings.ArgumentStructFields) []string {
+	result := make([]string, 0, 2)
+	if !isGroup(cmd) {
+		result = append(result, strings.TrimSpace(cmdPath+" "+fields.String()))
+	}
+	if p, ok := cmd.(Parent); ok && len(p.SubCommands()) > 0 {
+		result = append(result, cmdPath+" <command> [<args>...]")
+	}
+	return result
+}
+
+func fieldUsage(field xstrings.ArgumentStructField) string {
+	str := "<" + field.Name + ">"
+	if field.Variadic {
+		str += "..."
+	}
+	if field.Optional {
+		str = "[" + str + "]"
+	}
+	return str + " " + typeName(field)
+}
+
+func optionUsage(field xstrings.ArgumentStructField) string {
+	str := "    "
+	if field.Short != 0 {
+		str = "-" + string(field.Short)
+		if field.Long != "" {
+			str += ", "
+		}
+	}
+	if field.Long != "" {
+		str += "--" + field.Long
+	}
+	if !field.Boolean {
+		str += " " + typeName(field)
+	}
+	return str
+}
+
+func fieldDescription(field xstrings.ArgumentStructField) string {
+	str := field.Help
+	if field.Default != "" {
+		str = strings.TrimSpace(fmt.Sprintf("%s (default %q)", str, field.Default))
+	}
+	if field.LongHelp != "" {
+		str += "\n\n" + field.LongHelp
+	}
+	return str
+}
+
+func typeName(field xstrings.ArgumentStructField) string {
+	if field.Type == nil {
+		return "value"
+	}
+	typ := field.Type
+	if field.Variadic {
+		typ = typ.Elem()
+	}
+	if name := typ.Name(); name != "" {
+		return name
+	}
+	return typ.String()
+}
+
+// renderTable renders rows in two columns. The second column is wrapped to width,
+// and it begins on the next line if the first column is too wide.
+func renderTable(rows [][2]string, width int) string {
+	col := 0
+	for _, row := range rows {
+		if l := utf8.RuneCountInString(row[0]); l > col {
+			col = l
+		}
+	}
+	if maxCol := width / 3; col > maxCol {
+		col = maxCol
+	}
+	indent := strings.Repeat(" ", 2+col+2)
+
+	b := &strings.Builder{}
+	for _, row := range rows {
+		first := "  " + row[0]
+		paragraphs := splitParagraphs(row[1])
+		if len(paragraphs) <= 0 {
+			b.WriteString(first + "\n")
+			continue
+		}
+		if l := utf8.RuneCountInString(row[0]); l <= col {
+			text := wrapText(paragraphs[0], width, indent, indent)
+			b.WriteString(first + strings.Repeat(" ", col-l+2) + strings.TrimPrefix(text, indent))
+		} else {
+			b.WriteString(first + "\n")
+			b.WriteString(wrapText(paragraphs[0], width, indent, indent))
+		}
+		for _, paragraph := range paragraphs[1:] {
+			b.WriteString(wrapText(paragraph, width, indent, indent))
+		}
+	}
+	return b.String()
+}
+
+// wrapText wraps text to width by words, and terminates it with a new line.
+// The first line begins with indent, and the others begin with hangingIndent.
+func wrapText(text string, width int, indent, hangingIndent string) string {
+	b := &strings.Builder{}
+	line := indent
+	empty := true
+	for _, word := range strings.Fields(text) {
+		if !empty && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			b.WriteString(line + "\n")
+			line = hangingIndent
+			empty = true
+		}
+		if !empty {
+			line += " "
+		}
+		line += word
+		empty = false
+	}
+	b.WriteString(line + "\n")
+	return b.String()
+}
+
+// splitParagraphs splits text by blank lines, and returns the non-empty paragraphs.
+func splitParagraphs(text string) []string {
+	result := make([]string, 0, 8)
+	for _, paragraph := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			result = append(result, paragraph)
+		}
+	}
+	return result
+}
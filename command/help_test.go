@@ -0,0 +1,109 @@
+package command
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type helpArgs struct {
+	CmdName string
+	Role    string   `option:"role,r" default:"user" help:"role of the user"`
+	Force   bool     `option:"force,f" help:"overwrite the existing user\nThe existing user is deleted with all of its data."`
+	Name    string   `help:"name of the user"`
+	Groups  []string `help:"groups which the user is added to"`
+}
+
+func (a *helpArgs) CmdHelp() Help {
+	return Help{
+		Short:    "Add a user.",
+		Long:     "Adds a user into the user database.\n\nThe user can log in after it is added.",
+		Examples: []string{"prog user add -r admin alice", "prog user add bob staff"},
+	}
+}
+
+func TestHelpRenderer(t *testing.T) {
+	cmd := NewWithRunFunc(&helpArgs{}, nil, 0, 0, 0, false, "add", "new")
+	buf := &bytes.Buffer{}
+	if err := (&HelpRenderer{Width: 60}).Render(buf, "prog user add", cmd); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	want := `Usage:
+  prog user add [-r|--role <Role=user>] [-f|--force] [<Name>
+      [<Groups>...]]
+
+Add a user.
+
+Adds a user into the user database.
+
+The user can log in after it is added.
+
+Aliases:
+  add, new
+
+Arguments:
+  [<Name>] string       name of the user
+  [<Groups>...] string  groups which the user is added to
+
+Options:
+  -r, --role string  role of the user (default "user")
+  -f, --force        overwrite the existing user
+                     The existing user is deleted with all
+                     of its data.
+
+Examples:
+  prog user add -r admin alice
+  prog user add bob staff
+`
+	if buf.String() != want {
+		t.Errorf("Render =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	group := NewGroup(WithHelp(NewNames(false, "user"), Help{Short: "Manage users."}), cmd)
+	buf.Reset()
+	if err := (&HelpRenderer{}).Render(buf, "prog user", group); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	want = `Usage:
+  prog user <command> [<args>...]
+
+Manage users.
+
+Commands:
+  add, new  Add a user.
+`
+	if buf.String() != want {
+		t.Errorf("Render =\n%s\nwant\n%s", buf.String(), want)
+	}
+	if got := GetHelp(group).Short; got != "Manage users." {
+		t.Errorf("GetHelp = %q", got)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	cases := []struct {
+		text   string
+		width  int
+		indent string
+		want   string
+	}{
+		{"", 10, "", "\n"},
+		{"a b c", 10, "", "a b c\n"},
+		{"aaa bbb ccc", 7, "", "aaa bbb\nccc\n"},
+		{"aaa bbb ccc", 7, "  ", "  aaa\n  bbb\n  ccc\n"},
+		{"aaaaaaaaaa b", 5, "", "aaaaaaaaaa\nb\n"},
+		{"çç çç", 5, "", "çç çç\n"},
+	}
+	for _, c := range cases {
+		if got := wrapText(c.text, c.width, c.indent, c.indent); got != c.want {
+			t.Errorf("wrapText(%q, %d, %q) = %q, want %q", c.text, c.width, c.indent, got, c.want)
+		}
+	}
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	got := splitParagraphs("\n a\nb \r\n\r\n\n\nc\n\n")
+	if want := []string{"a\nb", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitParagraphs = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package command
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// Invocation describes an execution of a command. Registry.Execute puts it into the context of the command,
+// so the command can use its streams instead of the standard ones, e.g. in a REPL or a test.
+type Invocation struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Args is the raw arguments including the command names.
+	Args []string
+
+	// CmdName is the name which the command is invoked by.
+	CmdName string
+
+	// ParentPath is the names which the parent commands are invoked by, from the top level command.
+	ParentPath []string
+}
+
+type invocationKey struct{}
+
+// WithInvocation returns a copy of ctx which carries inv.
+// The streams of inv are used by the commands which Registry.Execute runs with the returned context.
+func WithInvocation(ctx context.Context, inv *Invocation) context.Context {
+	return context.WithValue(ctx, invocationKey{}, inv)
+}
+
+// InvocationFromContext returns the Invocation which ctx carries.
+// The nil streams of the result are replaced with the standard ones. The result is never nil.
+func InvocationFromContext(ctx context.Context) *Invocation {
+	result := &Invocation{}
+	if inv, ok := ctx.Value(invocationKey{}).(*Invocation); ok && inv != nil {
+		*result = *inv
+	}
+	if result.Stdin == nil {
+		result.Stdin = os.Stdin
+	}
+	if result.Stdout == nil {
+		result.Stdout = os.Stdout
+	}
+	if result.Stderr == nil {
+		result.Stderr = os.Stderr
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestInvocation(t *testing.T) {
+	var got *Invocation
+	add := NewWithRunFunc(&testArgs{}, func(ctx context.Context) error {
+		got = InvocationFromContext(ctx)
+		_, err := got.Stdout.Write([]byte("added\n"))
+		return err
+	}, 0, 0, 0, false, "add", "new")
+	r := &Registry{}
+	r.MustRegister(NewGroup(NewNames(true, "user"), add))
+
+	stdout := &bytes.Buffer{}
+	ctx := WithInvocation(context.Background(), &Invocation{Stdout: stdout, Args: []string{"ignored"}})
+	if err := r.Execute(ctx, "USER", "new", "x"); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if stdout.String() != "added\n" {
+		t.Errorf("Stdout = %q, want %q", stdout.String(), "added\n")
+	}
+	if got.Stdin != os.Stdin || got.Stderr != os.Stderr {
+		t.Errorf("nil streams are not replaced with the standard ones")
+	}
+	if want := []string{"USER", "new", "x"}; !reflect.DeepEqual(got.Args, want) {
+		t.Errorf("Args = %q, want %q", got.Args, want)
+	}
+	if got.CmdName != "new" {
+		t.Errorf("CmdName = %q, want %q", got.CmdName, "new")
+	}
+	if want := []string{"USER"}; !reflect.DeepEqual(got.ParentPath, want) {
+		t.Errorf("ParentPath = %q, want %q", got.ParentPath, want)
+	}
+
+	inv := InvocationFromContext(context.Background())
+	if inv == nil || inv.Stdout != os.Stdout || inv.Args != nil {
+		t.Errorf("InvocationFromContext of empty context = %+v", inv)
+	}
+}
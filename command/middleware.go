@@ -0,0 +1,104 @@
+package command
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+)
+
+// ExecuteFunc runs cmd which is resolved from args. args are the raw arguments including the command names.
+type ExecuteFunc func(ctx context.Context, cmd Command, args ...string) error
+
+// Middleware wraps the execution of commands, e.g. for logging, authorization or audit trails.
+type Middleware func(next ExecuteFunc) ExecuteFunc
+
+// WithMiddleware returns a new Command which behaves like cmd, and is executed through mws by Registry.
+// The first one of mws is the outermost.
+func WithMiddleware(cmd Command, mws ...Middleware) Command {
+	m := make([]Middleware, len(mws))
+	copy(m, mws)
+	if _, ok := cmd.(Parent); ok {
+		return &middlewareParentStruct{
+			Command: cmd,
+			mws:     m,
+		}
+	}
+	return &middlewareStruct{
+		Command: cmd,
+		mws:     m,
+	}
+}
+
+type middlewareStruct struct {
+	Command
+	mws []Middleware
+}
+
+type middlewareParentStruct struct {
+	Command
+	mws []Middleware
+}
+
+func (m *middlewareParentStruct) SubCommands() []Command {
+	return m.Command.(Parent).SubCommands()
+}
+
+// getMiddlewares returns the middlewares which are attached to cmd by WithMiddleware.
+func getMiddlewares(cmd Command) []Middleware {
+	var result []Middleware
+	lookupCommand(cmd, func(ifc interface{}) bool {
+		switch x := ifc.(type) {
+		case *middlewareStruct:
+			result = append(result, x.mws...)
+		case *middlewareParentStruct:
+			result = append(result, x.mws...)
+		}
+		return false
+	})
+	return result
+}
+
+// chainMiddlewares returns the ExecuteFunc which runs f through mws. The first one of mws is the outermost.
+func chainMiddlewares(f ExecuteFunc, mws ...Middleware) ExecuteFunc {
+	for idx := len(mws) - 1; idx >= 0; idx-- {
+		f = mws[idx](f)
+	}
+	return f
+}
+
+// Recover returns a Middleware which recovers panics of the next ExecuteFunc, and returns them as PanicError.
+func Recover() Middleware {
+	return func(next ExecuteFunc) ExecuteFunc {
+		return func(ctx context.Context, cmd Command, args ...string) (err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					err = &PanicError{p, debug.Stack()}
+				}
+			}()
+			return next(ctx, cmd, args...)
+		}
+	}
+}
+
+// Duration returns a Middleware which measures the duration of the next ExecuteFunc, and calls f with it.
+func Duration(f func(cmd Command, args []string, d time.Duration, err error)) Middleware {
+	return func(next ExecuteFunc) ExecuteFunc {
+		return func(ctx context.Context, cmd Command, args ...string) error {
+			start := time.Now()
+			err := next(ctx, cmd, args...)
+			f(cmd, args, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// Timeout returns a Middleware which cancels the context of the next ExecuteFunc after d.
+func Timeout(d time.Duration) Middleware {
+	return func(next ExecuteFunc) ExecuteFunc {
+		return func(ctx context.Context, cmd Command, args ...string) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, cmd, args...)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	var trace []string
+	newMiddleware := func(name string) Middleware {
+		return func(next ExecuteFunc) ExecuteFunc {
+			return func(ctx context.Context, cmd Command, args ...string) error {
+				trace = append(trace, name+" "+strings.Join(args, " "))
+				err := next(ctx, cmd, args...)
+				trace = append(trace, "/"+name)
+				return err
+			}
+		}
+	}
+	add := NewWithRunFunc(&testArgs{}, func(ctx context.Context) error {
+		trace = append(trace, "run")
+		return nil
+	}, 0, 0, 0, false, "add")
+
+	r := &Registry{}
+	r.Use(newMiddleware("r1"), newMiddleware("r2"))
+	r.MustRegister(WithMiddleware(NewGroup(NewNames(false, "user"), WithMiddleware(add, newMiddleware("c"))), newMiddleware("g")))
+
+	if err := r.Execute(context.Background(), "user", "add", "x"); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	want := []string{"r1 user add x", "r2 user add x", "g user add x", "c user add x", "run", "/c", "/g", "/r2", "/r1"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %q, want %q", trace, want)
+	}
+	if _, ok := r.Commands()[0].(Parent); !ok {
+		t.Errorf("WithMiddleware of a group doesn't implement Parent")
+	}
+}
+
+func TestMiddlewareParentFields(t *testing.T) {
+	type serveArgs struct {
+		CmdName string
+		Port    int `option:"port,p"`
+		Host    string
+	}
+	var args serveArgs
+	var ran []serveArgs
+	mw := func(next ExecuteFunc) ExecuteFunc {
+		return next
+	}
+	serve := NewWithRunFunc(&args, func(ctx context.Context) error {
+		ran = append(ran, args)
+		return nil
+	}, 0, 0, 0, false, "serve")
+
+	r := &Registry{}
+	r.MustRegister(WithMiddleware(WithSubCommands(serve, newTestCmd("status")), mw))
+
+	if err := r.Execute(context.Background(), "serve", "-p", "8080", "localhost"); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	want := []serveArgs{{"serve", 8080, "localhost"}}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran = %+v, want %+v", ran, want)
+	}
+	usage, err := r.getHandler().ParameterUsage(r.Commands()[0])
+	if want := "[-p|--port <Port>] [<Host>]"; err != nil || usage != want {
+		t.Errorf("ParameterUsage = %q, %v, want %q", usage, err, want)
+	}
+	if err := r.Execute(context.Background(), "serve", "status"); err != nil || len(ran) != 1 {
+		t.Errorf("Execute of sub command = %v, ran %+v", err, ran)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	f := chainMiddlewares(func(ctx context.Context, cmd Command, args ...string) error {
+		panic("boom")
+	}, Recover())
+	err := f(context.Background(), nil)
+	var e *PanicError
+	if !errors.As(err, &e) || e.Value() != "boom" || len(e.Stack()) <= 0 {
+		t.Errorf("error = %v, want *PanicError", err)
+	}
+}
+
+func TestTimeoutAndDuration(t *testing.T) {
+	var d time.Duration
+	var durationErr error
+	f := chainMiddlewares(func(ctx context.Context, cmd Command, args ...string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, Duration(func(cmd Command, args []string, d2 time.Duration, err error) {
+		d, durationErr = d2, err
+	}), Timeout(10*time.Millisecond))
+	err := f(context.Background(), nil)
+	if !errors.Is(err, context.DeadlineExceeded) || durationErr != err {
+		t.Errorf("error = %v, %v, want %v", err, durationErr, context.DeadlineExceeded)
+	}
+	if d < 10*time.Millisecond {
+		t.Errorf("duration = %v, want at least 10ms", d)
+	}
+}
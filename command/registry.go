@@ -0,0 +1,152 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Registry holds a set of commands with unique names, and dispatches arguments to them.
+type Registry struct {
+	Handler *Handler
+
+	mu   sync.RWMutex
+	cmds []Command
+	mws  []Middleware
+}
+
+// Register adds cmds into the registry. It returns DuplicateCommandError without adding any command
+// if a name of a command conflicts with an already registered command, or with another one in cmds.
+func (r *Registry) Register(cmds ...Command) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registered := make([]Command, len(r.cmds), len(r.cmds)+len(cmds))
+	copy(registered, r.cmds)
+	for _, cmd := range cmds {
+		if err := checkConflict(registered, cmd); err != nil {
+			return err
+		}
+		if err := checkSubConflicts(cmd); err != nil {
+			return err
+		}
+		registered = append(registered, cmd)
+	}
+
+	r.cmds = registered
+	return nil
+}
+
+// MustRegister is similar to Register, but panics on error.
+func (r *Registry) MustRegister(cmds ...Command) {
+	if err := r.Register(cmds...); err != nil {
+		panic(err)
+	}
+}
+
+// Commands returns the registered commands in registration order.
+func (r *Registry) Commands() []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]Command, len(r.cmds))
+	copy(result, r.cmds)
+	return result
+}
+
+// Use appends mws to the middlewares which wrap every execution of the registry.
+// The middlewares of the registry are outer than the middlewares of the commands.
+func (r *Registry) Use(mws ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mws = append(r.mws[:len(r.mws):len(r.mws)], mws...)
+}
+
+// Find finds the leaf command by args.
+func (r *Registry) Find(args ...string) (Command, error) {
+	path, err := r.getHandler().FindPath(r.Commands(), args...)
+	if err != nil {
+		return nil, err
+	}
+	return path[len(path)-1], nil
+}
+
+// Execute finds the leaf command by args, unmarshals its arguments into it and runs it.
+// It returns UnknownCommandError if the command is not found,
+// and ArgumentError if args can not be unmarshaled.
+// The command runs through the middlewares of the registry, and then the middlewares of the commands on its path.
+// The context of the command carries Invocation, whose streams are taken from the Invocation of ctx.
+func (r *Registry) Execute(ctx context.Context, args ...string) error {
+	h := r.getHandler()
+
+	path, err := h.FindPath(r.Commands(), args...)
+	if err != nil {
+		return err
+	}
+	depth := len(path) - 1
+	cmd := path[depth]
+
+	if err := h.Unmarshal(cmd, args[depth:]...); err != nil {
+		return &ArgumentError{strings.Join(args[:depth+1], " "), err}
+	}
+
+	r.mu.RLock()
+	mws := r.mws
+	r.mu.RUnlock()
+	for _, cmd2 := range path {
+		mws = append(mws[:len(mws):len(mws)], getMiddlewares(cmd2)...)
+	}
+
+	inv := InvocationFromContext(ctx)
+	inv.Args = append([]string(nil), args...)
+	inv.CmdName = args[depth]
+	inv.ParentPath = append([]string(nil), args[:depth]...)
+	ctx = WithInvocation(ctx, inv)
+
+	return chainMiddlewares(func(ctx context.Context, cmd Command, args ...string) error {
+		return cmd.Run(ctx)
+	}, mws...)(ctx, cmd, args...)
+}
+
+func (r *Registry) getHandler() *Handler {
+	if r.Handler == nil {
+		return &Handler{}
+	}
+	return r.Handler
+}
+
+// checkConflict returns DuplicateCommandError if any name of cmd conflicts with cmds.
+func checkConflict(cmds []Command, cmd Command) error {
+	for _, cmd2 := range cmds {
+		for _, cmdName := range cmd.CmdNames() {
+			if cmd2.Is(cmdName) {
+				return &DuplicateCommandError{cmdName, nil}
+			}
+		}
+		for _, cmdName := range cmd2.CmdNames() {
+			if cmd.Is(cmdName) {
+				return &DuplicateCommandError{cmdName, nil}
+			}
+		}
+	}
+	return nil
+}
+
+// checkSubConflicts checks conflicts between sub commands of cmd recursively.
+func checkSubConflicts(cmd Command) error {
+	p, ok := cmd.(Parent)
+	if !ok {
+		return nil
+	}
+	subCmds := p.SubCommands()
+	for idx, subCmd := range subCmds {
+		if err := checkConflict(subCmds[:idx], subCmd); err != nil {
+			return err
+		}
+		if err := checkSubConflicts(subCmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/goinsane/xstrings"
+)
+
+func TestRegistryRegister(t *testing.T) {
+	r := &Registry{}
+	if err := r.Register(newTestCmd("start", "run"), newTestCmd("stop")); err != nil {
+		t.Fatalf("Register error: %v", err)
+	}
+	cases := []struct {
+		cmds []Command
+		name string
+	}{
+		{[]Command{newTestCmd("run")}, "run"},
+		{[]Command{newTestCmd("status"), newTestCmd("stat", "status")}, "status"},
+		{[]Command{NewWithRunFunc(&testArgs{}, nil, 0, 0, 0, true, "STOP")}, "stop"},
+		{[]Command{NewWithRunFunc(&testArgs{}, nil, 0, 0, 0, false, "Start"), NewWithRunFunc(&testArgs{}, nil, 0, 0, 0, true, "START")}, "start"},
+	}
+	for _, c := range cases {
+		err := r.Register(c.cmds...)
+		var e *DuplicateCommandError
+		if !errors.As(err, &e) || e.Name() != c.name {
+			t.Errorf("Register(%q) error = %v, want duplicate %q", getCmdPath(c.cmds), err, c.name)
+		}
+	}
+	if got, want := getCmdPath(r.Commands()), []string{"start", "stop"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Commands = %q, want %q", got, want)
+	}
+}
+
+func TestRegistryExecute(t *testing.T) {
+	type addArgs struct {
+		CmdName string
+		Count   int
+	}
+	var ran []addArgs
+	add := &addArgs{}
+	r := &Registry{}
+	r.MustRegister(NewWithRunFunc(add, func(ctx context.Context) error {
+		ran = append(ran, *add)
+		return nil
+	}, 0, 2, 0, false, "add"))
+
+	if err := r.Execute(context.Background(), "add", "2"); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if want := []addArgs{{"add", 2}}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("Execute ran %+v, want %+v", ran, want)
+	}
+
+	var e *UnknownCommandError
+	if err := r.Execute(context.Background(), "del"); !errors.As(err, &e) {
+		t.Errorf("Execute error = %v, want *UnknownCommandError", err)
+	}
+	var e2 *ArgumentError
+	var e3 *xstrings.ArgumentParseError
+	if err := r.Execute(context.Background(), "add", "x"); !errors.As(err, &e2) || e2.Name() != "add" || !errors.As(err, &e3) {
+		t.Errorf("Execute error = %v, want *ArgumentError", err)
+	}
+	var e4 *xstrings.MissingArgumentError
+	if err := r.Execute(context.Background(), "add"); !errors.As(err, &e4) {
+		t.Errorf("Execute error = %v, want *xstrings.MissingArgumentError", err)
+	}
+	if err := r.Execute(context.Background()); err != ErrCommandNotSet {
+		t.Errorf("Execute error = %v, want %v", err, ErrCommandNotSet)
+	}
+}
+
+func TestRegistryExecuteSubCommands(t *testing.T) {
+	type userArgs struct {
+		CmdName string
+		Name    string
+	}
+	var ran []string
+	newUserCmd := func(cmdName string) Command {
+		args := &userArgs{}
+		return NewWithRunFunc(args, func(ctx context.Context) error {
+			ran = append(ran, args.CmdName+" "+args.Name)
+			return nil
+		}, 0, 0, 0, false, cmdName)
+	}
+	r := &Registry{}
+	r.MustRegister(NewGroup(NewNames(false, "user"), newUserCmd("add"), newUserCmd("del")))
+
+	if err := r.Execute(context.Background(), "user", "add", "x"); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if err := r.Execute(context.Background(), "user", "del", "y"); err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if want := []string{"add x", "del y"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("Execute ran %q, want %q", ran, want)
+	}
+
+	if err := r.Execute(context.Background(), "user"); err != ErrSubCommandNotSet {
+		t.Errorf("Execute error = %v, want %v", err, ErrSubCommandNotSet)
+	}
+	var e *UnknownCommandError
+	if err := r.Execute(context.Background(), "user", "mod"); !errors.As(err, &e) || e.Name() != "mod" {
+		t.Errorf("Execute error = %v, want *UnknownCommandError", err)
+	}
+
+	var e2 *DuplicateCommandError
+	err := r.Register(NewGroup(NewNames(false, "group"), newTestCmd("add"), NewGroup(NewNames(false, "sub"), newTestCmd("a"), newTestCmd("a"))))
+	if !errors.As(err, &e2) || e2.Name() != "a" {
+		t.Errorf("Register error = %v, want duplicate %q", err, "a")
+	}
+}
@@ -0,0 +1,160 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/goinsane/xstrings"
+)
+
+// REPL is a read-eval-print loop which reads commands line by line and executes them through Registry.
+// The built-in commands "help [command...]" and "exit" are handled before the registered commands.
+type REPL struct {
+	Registry  *Registry
+	Arguments *xstrings.Arguments
+
+	// Prompt is written before reading each command. If Prompt is empty, "> " is used.
+	Prompt string
+
+	// Interrupt cancels the context of the running command when it receives a signal, e.g. by signal.Notify.
+	// The signals which are received while no command is running are discarded.
+	Interrupt <-chan os.Signal
+}
+
+// Run runs the loop by reading commands from in, and writing prompts and errors to out.
+// It returns nil on EOF or exit command. The commands write their output to out through Invocation.
+func (r *REPL) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	arguments := r.Arguments
+	if arguments == nil {
+		arguments = &xstrings.Arguments{}
+	}
+
+	prompt := r.Prompt
+	if prompt == "" {
+		prompt = "> "
+	}
+
+	ar := xstrings.NewArgumentsReader(in, arguments)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(out, prompt); err != nil {
+			return err
+		}
+
+		args, err := ar.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				_, _ = io.WriteString(out, "\n")
+				return nil
+			}
+			var e *xstrings.ParseError
+			if errors.As(err, &e) {
+				if caret := e.Caret(); caret != "" {
+					_, _ = fmt.Fprintln(out, caret)
+				}
+				_, _ = fmt.Fprintln(out, err)
+				continue
+			}
+			return err
+		}
+
+		switch args[0] {
+		case "exit":
+			return nil
+		case "help":
+			err = r.help(out, args[1:]...)
+		default:
+			err = r.execute(ctx, out, args...)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintln(out, err)
+		}
+	}
+}
+
+func (r *REPL) execute(ctx context.Context, out io.Writer, args ...string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.drainInterrupt()
+	done, stopped := make(chan struct{}), make(chan struct{})
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+	go func() {
+		defer close(stopped)
+		select {
+		case <-r.Interrupt:
+			cancel()
+		case <-done:
+		}
+	}()
+
+	inv := InvocationFromContext(ctx)
+	inv.Stdout = out
+	inv.Stderr = out
+	return r.getRegistry().Execute(WithInvocation(ctx, inv), args...)
+}
+
+// drainInterrupt discards the pending signals of Interrupt.
+func (r *REPL) drainInterrupt() {
+	for {
+		select {
+		case _, ok := <-r.Interrupt:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (r *REPL) help(out io.Writer, args ...string) error {
+	registry := r.getRegistry()
+	h := registry.getHandler()
+
+	if len(args) <= 0 {
+		for _, cmd := range registry.Commands() {
+			usage, err := h.Usage(cmd, "", "  ")
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(out, usage); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path, err := h.FindPath(registry.Commands(), args...)
+	if err != nil {
+		return err
+	}
+	depth := len(path) - 1
+	prefix := ""
+	if depth > 0 {
+		prefix = strings.Join(args[:depth], " ") + " "
+	}
+	usage, err := h.Usage(path[depth], args[depth], prefix)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, usage)
+	return err
+}
+
+func (r *REPL) getRegistry() *Registry {
+	if r.Registry == nil {
+		return &Registry{}
+	}
+	return r.Registry
+}
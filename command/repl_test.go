@@ -0,0 +1,122 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/goinsane/xstrings"
+)
+
+func newTestREPL(interrupt <-chan os.Signal, started chan<- struct{}) (*REPL, *[]string) {
+	var ran []string
+	echo := &testArgs{}
+	wait := &testArgs{}
+	registry := &Registry{}
+	registry.MustRegister(
+		NewWithRunFunc(echo, func(ctx context.Context) error {
+			ran = append(ran, strings.Join(echo.Args, " "))
+			_, err := io.WriteString(InvocationFromContext(ctx).Stdout, strings.Join(echo.Args, " ")+"\n")
+			return err
+		}, 0, 0, 0, false, "echo"),
+		NewWithRunFunc(wait, func(ctx context.Context) error {
+			ran = append(ran, "wait")
+			if len(wait.Args) > 0 {
+				return ctx.Err()
+			}
+			if started != nil {
+				started <- struct{}{}
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		}, 0, 0, 0, false, "wait"),
+	)
+	return &REPL{
+		Registry:  registry,
+		Arguments: &xstrings.Arguments{Shell: true},
+		Interrupt: interrupt,
+	}, &ran
+}
+
+func TestREPL(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"echo a 'b c'\n", "> a b c\n> \n"},
+		{"echo a\nexit\necho b\n", "> a\n> "},
+		{"help\n", "> " + "  echo [<Args>...]\n  wait [<Args>...]\n> \n"},
+		{"help wait\n", "> wait [<Args>...]\n> \n"},
+		{"foo\n", "> unknown command \"foo\"\n> \n"},
+		{"echo 'a\n", "> echo 'a\n     ^\nparse error at line 1 column 6: unterminated quote\n> \n"},
+		{"echo a", "> a\n> \n"},
+		{"", "> \n"},
+	}
+	for _, c := range cases {
+		repl, _ := newTestREPL(nil, nil)
+		out := &bytes.Buffer{}
+		if err := repl.Run(context.Background(), strings.NewReader(c.in), out); err != nil {
+			t.Errorf("Run(%q) error: %v", c.in, err)
+			continue
+		}
+		if got := out.String(); got != c.want {
+			t.Errorf("Run(%q) output = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestREPLInterrupt(t *testing.T) {
+	interrupt := make(chan os.Signal, 1)
+	started := make(chan struct{})
+	repl, ran := newTestREPL(interrupt, started)
+
+	interrupt <- os.Interrupt
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- repl.Run(context.Background(), inR, outW)
+		_ = outW.Close()
+	}()
+
+	buf := make([]byte, 1024)
+	readOutput := func() string {
+		n, err := outR.Read(buf)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		return string(buf[:n])
+	}
+
+	if got := readOutput(); got != "> " {
+		t.Fatalf("output = %q, want prompt", got)
+	}
+	_, _ = io.WriteString(inW, "wait now\n")
+	if got := readOutput(); got != "> " {
+		t.Fatalf("output = %q, want prompt, the pending signal is not discarded", got)
+	}
+
+	_, _ = io.WriteString(inW, "wait\n")
+	<-started
+	interrupt <- os.Interrupt
+	if got := readOutput(); got != "context canceled\n" {
+		t.Fatalf("output = %q, want %q", got, "context canceled\n")
+	}
+	if got := readOutput(); got != "> " {
+		t.Fatalf("output = %q, want prompt", got)
+	}
+
+	_ = inW.Close()
+	if got := readOutput(); got != "\n" {
+		t.Fatalf("output = %q, want new line", got)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("Run error: %v", err)
+	}
+	if want := "wait wait"; strings.Join(*ran, " ") != want {
+		t.Errorf("ran %q, want %q", *ran, want)
+	}
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"sort"
+	"strings"
+)
+
+// suggestCmdNames returns the command names of cmds which are close to cmdName, the closest first.
+// A command name is close if it begins with cmdName, or its edit distance to cmdName is at most maxDistance.
+func suggestCmdNames(cmds []Command, cmdName string, maxDistance int) []string {
+	if maxDistance < 0 || cmdName == "" {
+		return nil
+	}
+
+	type suggestion struct {
+		name     string
+		prefix   bool
+		distance int
+	}
+	suggestions := make([]suggestion, 0, len(cmds))
+	seen := make(map[string]struct{}, len(cmds))
+	for _, cmd := range cmds {
+		fold := cmd.CmdNamesFold()
+		for _, cmdName2 := range cmd.CmdNames() {
+			if _, ok := seen[cmdName2]; ok {
+				continue
+			}
+			x, y := cmdName, cmdName2
+			if fold {
+				x, y = strings.ToLower(x), strings.ToLower(y)
+			}
+			s := suggestion{
+				name:     cmdName2,
+				prefix:   strings.HasPrefix(y, x),
+				distance: editDistance(x, y),
+			}
+			if !s.prefix && s.distance > maxDistance {
+				continue
+			}
+			seen[cmdName2] = struct{}{}
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].prefix != suggestions[j].prefix {
+			return suggestions[i].prefix
+		}
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	result := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		result = append(result, s.name)
+	}
+	return result
+}
+
+// editDistance returns the Levenshtein distance between x and y in runes.
+func editDistance(x, y string) int {
+	a, b := []rune(x), []rune(y)
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cur := row[j]
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			row[j] = minInt(minInt(row[j]+1, row[j-1]+1), prev+cost)
+			prev = cur
+		}
+	}
+	return row[len(b)]
+}
+
+func minInt(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		x, y string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"start", "start", 0},
+		{"strat", "start", 2},
+		{"stat", "start", 1},
+		{"kitten", "sitting", 3},
+		{"çay", "cay", 1},
+	}
+	for _, c := range cases {
+		if got := editDistance(c.x, c.y); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", c.x, c.y, got, c.want)
+		}
+	}
+}
+
+func TestSuggestCmdNames(t *testing.T) {
+	cmds := []Command{
+		newTestCmd("start"),
+		newTestCmd("stop"),
+		newTestCmd("status", "st"),
+		NewWithRunFunc(&testArgs{}, nil, 0, 0, 0, true, "Restart"),
+	}
+	cases := []struct {
+		cmdName     string
+		maxDistance int
+		want        []string
+	}{
+		{"strat", 2, []string{"start"}},
+		{"stat", 2, []string{"status", "start", "stop", "st"}},
+		{"sta", 1, []string{"start", "status", "st"}},
+		{"RESTAT", 1, []string{"Restart"}},
+		{"xyz", 2, []string{}},
+		{"strat", -1, nil},
+		{"", 2, nil},
+	}
+	for _, c := range cases {
+		got := suggestCmdNames(cmds, c.cmdName, c.maxDistance)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("suggestCmdNames(%q, %d) = %q, want %q", c.cmdName, c.maxDistance, got, c.want)
+		}
+	}
+
+	_, err := (&Handler{}).Find(cmds, "strat")
+	if want := `unknown command "strat", did you mean "start"?`; err == nil || err.Error() != want {
+		t.Errorf("Find error = %v, want %s", err, want)
+	}
+	_, err = (&Handler{SuggestionDistance: -1}).Find(cmds, "strat")
+	if want := `unknown command "strat"`; err == nil || err.Error() != want {
+		t.Errorf("Find error = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,39 @@
+# bash completion for my-prog
+
+_my_prog() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" node=0 consumed=1 word i
+	local -a candidates=()
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		word="${COMP_WORDS[i]}"
+		case "$node/$word" in
+		0/user) node=1 ;;
+		0/[sS][eE][rR][vV][eE]) node=4 ;;
+		1/add) node=2 ;;
+		1/del) node=3 ;;
+		1/rm) node=3 ;;
+		*) consumed=0; break ;;
+		esac
+	done
+	if ((consumed)); then
+		case "$node" in
+		0) candidates+=(user Serve) ;;
+		1) candidates+=(add del rm) ;;
+		esac
+	fi
+	if [[ "$cur" == -* ]]; then
+		case "$node" in
+		2) candidates+=(--role -r --force -f) ;;
+		4) candidates+=(--port -p) ;;
+		esac
+	fi
+	COMPREPLY=()
+	for word in "${candidates[@]}"; do
+		[[ "$word" == "$cur"* ]] && COMPREPLY+=("$word")
+	done
+	if ((${#COMPREPLY[@]} == 0)); then
+		local IFS=$'\n'
+		COMPREPLY=($("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+	fi
+}
+
+complete -o default -F _my_prog my-prog
@@ -0,0 +1,61 @@
+# fish completion for my-prog
+
+function __my_prog_complete
+	set -l words (commandline -opc)
+	set -l cur (commandline -ct)
+	set -l node 0
+	set -l consumed 1
+	for word in $words[2..-1]
+		set -l next
+		switch "$node/"$word
+			case '0/user'
+				set next 1
+			case '1/add'
+				set next 2
+			case '1/del'
+				set next 3
+			case '1/rm'
+				set next 3
+		end
+		if test -z "$next"
+			switch "$node/"(string lower -- $word)
+				case '0/serve'
+					set next 4
+			end
+		end
+		if test -z "$next"
+			set consumed 0
+			break
+		end
+		set node $next
+	end
+	set -l candidates
+	if test $consumed -eq 1
+		switch $node
+			case 0
+				set candidates 'user' 'Serve'
+			case 1
+				set candidates 'add' 'del' 'rm'
+		end
+	end
+	if string match -q -- '-*' $cur
+		switch $node
+			case 2
+				set candidates $candidates '--role' '-r' '--force' '-f'
+			case 4
+				set candidates $candidates '--port' '-p'
+		end
+	end
+	set -l reply
+	for word in $candidates
+		if string match -q -- (string escape --style=wildcard -- $cur)'*' $word
+			set reply $reply $word
+		end
+	end
+	if test (count $reply) -eq 0
+		set reply ($words[1] __complete $words[2..-1] $cur 2>/dev/null)
+	end
+	printf '%s\n' $reply
+end
+
+complete -c 'my-prog' -f -a '(__my_prog_complete)'
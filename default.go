@@ -17,6 +17,10 @@ var (
 
 	DefaultIndent          = ""
 	DefaultMultiLinePrefix = ""
+
+	DefaultKeyValueSep = "="
+
+	DefaultKeySep = "_"
 )
 
 var (
@@ -29,3 +33,10 @@ var (
 		ComplexPrec: -2,
 	}
 )
+
+var (
+	DefaultOptionTagKey  = "option"
+	DefaultDefaultTagKey = "default"
+	DefaultHelpTagKey    = "help"
+	DefaultCodecTagKey   = "xstrings"
+)
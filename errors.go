@@ -3,6 +3,8 @@ package xstrings
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 )
 
 var (
@@ -11,23 +13,49 @@ var (
 	ErrValueMustBeStruct           = errors.New("value must be struct")
 	ErrArgumentCountExceeded       = errors.New("argument count exceeded")
 	ErrArgumentStructFieldNotFound = errors.New("argument struct field not found")
+	ErrUnterminatedQuote           = errors.New("unterminated quote")
+	ErrTrailingBackslash           = errors.New("trailing backslash")
+	ErrBadSubstitution             = errors.New("bad substitution")
+	ErrInvalidConstraint           = errors.New("invalid constraint")
+	ErrUnknownCodecOption          = errors.New("unknown codec option")
+	ErrUnknownTimeUnit             = errors.New("unknown time unit")
+	ErrMissingKeyValueSep          = errors.New("missing key value separator")
+	ErrCanNotExpressOptionValue    = errors.New("can not express option value")
 )
 
 // ParseError is type of error
 type ParseError struct {
-	err error
+	err    error
+	input  string
+	offset int
 }
 
 // newParseError wraps err into ParseError
 func newParseError(err error) error {
 	return &ParseError{
-		err: err,
+		err:    err,
+		offset: -1,
+	}
+}
+
+// newParseErrorAt wraps err into ParseError with the byte offset of the failure in input
+func newParseErrorAt(err error, input string, offset int) error {
+	if offset > len(input) {
+		offset = len(input)
+	}
+	return &ParseError{
+		err:    err,
+		input:  input,
+		offset: offset,
 	}
 }
 
 // Error is implementation of error
 func (e *ParseError) Error() string {
 	str := "parse error"
+	if e.offset >= 0 {
+		str = fmt.Sprintf("%s at line %d column %d", str, e.Line(), e.Column())
+	}
 	if e.err == nil || e.err.Error() == "" {
 		return str
 	}
@@ -39,6 +67,53 @@ func (e *ParseError) Unwrap() error {
 	return e.err
 }
 
+// Offset returns the byte offset of the failure in the input, or -1 if it is unknown
+func (e *ParseError) Offset() int {
+	return e.offset
+}
+
+// Line returns the 1-based line number of the failure, or 0 if it is unknown
+func (e *ParseError) Line() int {
+	if e.offset < 0 {
+		return 0
+	}
+	return strings.Count(e.input[:e.offset], "\n") + 1
+}
+
+// Column returns the 1-based rune column of the failure, or 0 if it is unknown
+func (e *ParseError) Column() int {
+	if e.offset < 0 {
+		return 0
+	}
+	lineStart := strings.LastIndexByte(e.input[:e.offset], '\n') + 1
+	return utf8.RuneCountInString(e.input[lineStart:e.offset]) + 1
+}
+
+// Caret renders the failed line of the input with a '^' marker under the failure.
+// It returns empty string if the position is unknown.
+func (e *ParseError) Caret() string {
+	if e.offset < 0 {
+		return ""
+	}
+	lineStart := strings.LastIndexByte(e.input[:e.offset], '\n') + 1
+	lineEnd := strings.IndexByte(e.input[e.offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(e.input)
+	} else {
+		lineEnd += e.offset
+	}
+	marker := make([]byte, 0, e.offset-lineStart+1)
+	for _, r := range e.input[lineStart:e.offset] {
+		if r == '\t' {
+			marker = append(marker, '\t')
+			continue
+		}
+		marker = append(marker, ' ')
+	}
+	marker = append(marker, '^')
+	return strings.TrimSuffix(e.input[lineStart:lineEnd], "\r") + "\n" + string(marker)
+}
+
 // FormatError is type of error
 type FormatError struct {
 	err error
@@ -113,3 +188,153 @@ func (e *ArgumentParseError) Unwrap() error {
 func (e *ArgumentParseError) Name() string {
 	return e.name
 }
+
+type ArgumentValidationError struct {
+	name       string
+	constraint string
+	value      interface{}
+	err        error
+}
+
+func (e *ArgumentValidationError) Error() string {
+	str := "argument"
+	if e.name != "" {
+		str = fmt.Sprintf("%s <%s>", str, e.name)
+	}
+	str = fmt.Sprintf("%s validation error", str)
+	if e.constraint != "" {
+		str = fmt.Sprintf("%s on %q", str, e.constraint)
+	}
+	if e.err == nil || e.err.Error() == "" {
+		return str
+	}
+	return fmt.Sprintf("%s: %v", str, e.err)
+}
+
+func (e *ArgumentValidationError) Unwrap() error {
+	return e.err
+}
+
+func (e *ArgumentValidationError) Name() string {
+	return e.name
+}
+
+// Constraint returns the failed constraint like "min=1"
+func (e *ArgumentValidationError) Constraint() string {
+	return e.constraint
+}
+
+// Value returns the offending value. It is the element for constraints applied to elements of arrays and slices.
+func (e *ArgumentValidationError) Value() interface{} {
+	return e.value
+}
+
+type UnknownOptionError struct {
+	name string
+	err  error
+}
+
+func (e *UnknownOptionError) Error() string {
+	str := "unknown option"
+	if e.name != "" {
+		str = fmt.Sprintf("%s %s", str, e.name)
+	}
+	if e.err == nil || e.err.Error() == "" {
+		return str
+	}
+	return fmt.Sprintf("%s: %v", str, e.err)
+}
+
+func (e *UnknownOptionError) Unwrap() error {
+	return e.err
+}
+
+func (e *UnknownOptionError) Name() string {
+	return e.name
+}
+
+type UnresolvedVariableError struct {
+	name string
+	err  error
+}
+
+func newUnresolvedVariableError(name string, message string) error {
+	e := &UnresolvedVariableError{
+		name: name,
+	}
+	if message != "" {
+		e.err = errors.New(message)
+	}
+	return e
+}
+
+func (e *UnresolvedVariableError) Error() string {
+	str := "unresolved variable"
+	if e.name != "" {
+		str = fmt.Sprintf("%s %q", str, e.name)
+	}
+	if e.err == nil || e.err.Error() == "" {
+		return str
+	}
+	return fmt.Sprintf("%s: %v", str, e.err)
+}
+
+func (e *UnresolvedVariableError) Unwrap() error {
+	return e.err
+}
+
+func (e *UnresolvedVariableError) Name() string {
+	return e.name
+}
+
+// KeyValueParseError is returned by KeyValueStruct when the value of a key can not be parsed.
+type KeyValueParseError struct {
+	key string
+	err error
+}
+
+func (e *KeyValueParseError) Error() string {
+	str := "key"
+	if e.key != "" {
+		str = fmt.Sprintf("%s %q", str, e.key)
+	}
+	str = fmt.Sprintf("%s parse error", str)
+	if e.err == nil || e.err.Error() == "" {
+		return str
+	}
+	return fmt.Sprintf("%s: %v", str, e.err)
+}
+
+func (e *KeyValueParseError) Unwrap() error {
+	return e.err
+}
+
+func (e *KeyValueParseError) Key() string {
+	return e.key
+}
+
+// KeyValueFormatError is returned by KeyValueStruct when the value of a key can not be formatted.
+type KeyValueFormatError struct {
+	key string
+	err error
+}
+
+func (e *KeyValueFormatError) Error() string {
+	str := "key"
+	if e.key != "" {
+		str = fmt.Sprintf("%s %q", str, e.key)
+	}
+	str = fmt.Sprintf("%s format error", str)
+	if e.err == nil || e.err.Error() == "" {
+		return str
+	}
+	return fmt.Sprintf("%s: %v", str, e.err)
+}
+
+func (e *KeyValueFormatError) Unwrap() error {
+	return e.err
+}
+
+func (e *KeyValueFormatError) Key() string {
+	return e.key
+}
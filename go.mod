@@ -1,3 +1,3 @@
 module github.com/goinsane/xstrings
 
-go 1.1
+go 1.18
@@ -0,0 +1,380 @@
+package xstrings
+
+import (
+	"bytes"
+	"encoding"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// KeyValue is a key and value pair.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// KeyValueStruct maps the fields of structs to key/value pairs, e.g. for env files or properties.
+// The fields of embedded structs are mapped as the fields of the outer struct, and the fields of nested structs
+// are mapped with the key of the nested struct and KeySep as prefix.
+// The fields are named like ArgumentStruct, and their values are parsed and formatted by Unmarshaler and Marshaler
+// with the codec options of the fields.
+type KeyValueStruct struct {
+	Unmarshaler              *Unmarshaler
+	Marshaler                *Marshaler
+	FieldNameBeginsLowerCase bool
+	FieldNameFold            bool
+	FieldTagKey              string
+
+	// KeySep joins the keys of nested structs and their fields. If KeySep is empty, DefaultKeySep is used.
+	KeySep string
+
+	// CodecTagKey is the struct tag key of the codec options of the field. See ArgumentStruct.CodecTagKey.
+	// If CodecTagKey is empty, DefaultCodecTagKey is used.
+	CodecTagKey string
+}
+
+// Unmarshal sets the fields of the struct which ifc points to from pairs. The keys which don't match
+// any field are ignored. If a key is repeated, the last one wins.
+func (k *KeyValueStruct) Unmarshal(ifc interface{}, pairs ...KeyValue) error {
+	return k.UnmarshalByValue(reflect.ValueOf(ifc), pairs...)
+}
+
+func (k *KeyValueStruct) UnmarshalByValue(val reflect.Value, pairs ...KeyValue) error {
+	val, err := getStructPtr(val)
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		fieldVal, sf, ok := k.find(val.Elem(), pair.Key)
+		if !ok {
+			continue
+		}
+		unmarshaler, err := k.getUnmarshaler(sf)
+		if err != nil {
+			return &KeyValueParseError{pair.Key, err}
+		}
+		if err := unmarshaler.UnmarshalByValue(pair.Value, fieldVal); err != nil {
+			if e, ok := err.(*ParseError); ok {
+				err = e.Unwrap()
+			}
+			return &KeyValueParseError{pair.Key, err}
+		}
+	}
+	return nil
+}
+
+// Marshal formats the fields of the struct which ifc is or points to into key/value pairs in field order.
+// The nested structs which are nil pointers are omitted.
+func (k *KeyValueStruct) Marshal(ifc interface{}) ([]KeyValue, error) {
+	return k.MarshalByValue(reflect.ValueOf(ifc))
+}
+
+func (k *KeyValueStruct) MarshalByValue(val reflect.Value) ([]KeyValue, error) {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, ErrNilPointer
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, ErrValueMustBeStruct
+	}
+
+	result := make([]KeyValue, 0, 64)
+	err := k.marshal(val, "", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Decode reads the document of KEY=value lines from r, and sets the fields of the struct which ifc points to.
+// Blank lines and the lines beginning with '#' or '!' are ignored. A line may begin with "export" and white space,
+// and the key may be separated from the value by ':' instead of '='. Double quoted values are unquoted
+// by Go syntax, and single quoted values are taken literally.
+func (k *KeyValueStruct) Decode(r io.Reader, ifc interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	pairs, err := ParseKeyValues(string(data))
+	if err != nil {
+		return err
+	}
+	return k.Unmarshal(ifc, pairs...)
+}
+
+// Encode writes the fields of the struct which ifc is or points to as a document of KEY=value lines to w.
+func (k *KeyValueStruct) Encode(w io.Writer, ifc interface{}) error {
+	pairs, err := k.Marshal(ifc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, FormatKeyValues(pairs...))
+	return err
+}
+
+func (k *KeyValueStruct) marshal(val reflect.Value, prefix string, result *[]KeyValue) error {
+	typ := val.Type()
+	for i, j := 0, typ.NumField(); i < j; i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		fieldVal := val.Field(i)
+		if k.isNested(sf.Type) {
+			if sf.Type.Kind() == reflect.Ptr {
+				if fieldVal.IsNil() {
+					continue
+				}
+				fieldVal = fieldVal.Elem()
+			}
+			if sf.Anonymous {
+				if err := k.marshal(fieldVal, prefix, result); err != nil {
+					return err
+				}
+				continue
+			}
+			fieldName, ok := k.getFieldName(sf)
+			if !ok {
+				continue
+			}
+			if err := k.marshal(fieldVal, prefix+fieldName+k.getKeySep(), result); err != nil {
+				return err
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		fieldName, ok := k.getFieldName(sf)
+		if !ok {
+			continue
+		}
+		key := prefix + fieldName
+		marshaler, err := k.getMarshaler(sf)
+		if err != nil {
+			return &KeyValueFormatError{key, err}
+		}
+		str, err := marshaler.MarshalByValue(fieldVal)
+		if err != nil {
+			if e, ok := err.(*FormatError); ok {
+				err = e.Unwrap()
+			}
+			return &KeyValueFormatError{key, err}
+		}
+		*result = append(*result, KeyValue{key, str})
+	}
+	return nil
+}
+
+// find finds the field of the struct val by key. The nested structs which are nil pointers are allocated
+// only if the field is found in them.
+func (k *KeyValueStruct) find(val reflect.Value, key string) (reflect.Value, reflect.StructField, bool) {
+	typ := val.Type()
+	for i, j := 0, typ.NumField(); i < j; i++ {
+		sf := typ.Field(i)
+		fieldVal := val.Field(i)
+		if !fieldVal.CanSet() && !(sf.Anonymous && k.isNested(sf.Type)) {
+			continue
+		}
+		if k.isNested(sf.Type) {
+			subKey := key
+			if !sf.Anonymous {
+				fieldName, ok := k.getFieldName(sf)
+				if !ok {
+					continue
+				}
+				prefix := fieldName + k.getKeySep()
+				if len(key) <= len(prefix) || !k.equalFold(key[:len(prefix)], prefix) {
+					continue
+				}
+				subKey = key[len(prefix):]
+			}
+			if sf.Type.Kind() != reflect.Ptr {
+				if result, resultSf, ok := k.find(fieldVal, subKey); ok {
+					return result, resultSf, true
+				}
+				continue
+			}
+			if !fieldVal.IsNil() {
+				if result, resultSf, ok := k.find(fieldVal.Elem(), subKey); ok {
+					return result, resultSf, true
+				}
+				continue
+			}
+			if !fieldVal.CanSet() {
+				continue
+			}
+			newVal := reflect.New(sf.Type.Elem())
+			if result, resultSf, ok := k.find(newVal.Elem(), subKey); ok {
+				fieldVal.Set(newVal)
+				return result, resultSf, true
+			}
+			continue
+		}
+		fieldName, ok := k.getFieldName(sf)
+		if !ok {
+			continue
+		}
+		if k.equalFold(fieldName, key) {
+			return fieldVal, sf, true
+		}
+	}
+	return reflect.Value{}, reflect.StructField{}, false
+}
+
+// isNested reports whether typ is a struct, or a pointer to struct, which is mapped field by field.
+// The structs which are handled by the codecs, time.Time and text marshalers are mapped as values.
+func (k *KeyValueStruct) isNested(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == timeType {
+		return false
+	}
+	if typ.Implements(textMarshalerType) || reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return false
+	}
+	if k.Unmarshaler != nil && k.Unmarshaler.Codecs != nil {
+		if _, ok := k.Unmarshaler.Codecs.Decoder(typ); ok {
+			return false
+		}
+	}
+	if k.Marshaler != nil && k.Marshaler.Codecs != nil {
+		if _, ok := k.Marshaler.Codecs.Encoder(typ); ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (k *KeyValueStruct) getKeySep() string {
+	if k.KeySep == "" {
+		return DefaultKeySep
+	}
+	return k.KeySep
+}
+
+func (k *KeyValueStruct) getFieldName(sf reflect.StructField) (string, bool) {
+	return getFieldName(sf, k.FieldNameBeginsLowerCase, k.FieldTagKey)
+}
+
+func (k *KeyValueStruct) equalFold(x, y string) bool {
+	return equalFieldName(x, y, k.FieldNameFold)
+}
+
+func (k *KeyValueStruct) getUnmarshaler(sf reflect.StructField) (*Unmarshaler, error) {
+	return getFieldUnmarshaler(k.Unmarshaler, k.CodecTagKey, sf)
+}
+
+func (k *KeyValueStruct) getMarshaler(sf reflect.StructField) (*Marshaler, error) {
+	return getFieldMarshaler(k.Marshaler, k.CodecTagKey, sf)
+}
+
+// ParseKeyValues parses the document of KEY=value lines. See KeyValueStruct.Decode for the syntax.
+func ParseKeyValues(str string) ([]KeyValue, error) {
+	result := make([]KeyValue, 0, 64)
+	for _, start := range getLineStarts(str) {
+		line := str[start:]
+		if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSuffix(line, "\r")
+
+		trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+		offset := start + len(line) - len(trimmed)
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+			continue
+		}
+		if rest := strings.TrimPrefix(trimmed, "export"); rest != trimmed && strings.IndexFunc(rest, unicode.IsSpace) == 0 {
+			exported := strings.TrimLeftFunc(rest, unicode.IsSpace)
+			offset += len(trimmed) - len(exported)
+			trimmed = exported
+		}
+
+		idx := strings.IndexAny(trimmed, "=:")
+		if idx < 0 {
+			return nil, newParseErrorAt(ErrMissingKeyValueSep, str, offset)
+		}
+		key := strings.TrimSpace(trimmed[:idx])
+		value := strings.TrimSpace(trimmed[idx+1:])
+		valueOffset := offset + len(trimmed) - len(strings.TrimLeftFunc(trimmed[idx+1:], unicode.IsSpace))
+
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, newParseErrorAt(err, str, valueOffset)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, newParseErrorAt(ErrUnterminatedQuote, str, valueOffset)
+			}
+			value = value[1 : len(value)-1]
+		}
+
+		result = append(result, KeyValue{key, value})
+	}
+	return result, nil
+}
+
+// FormatKeyValues formats pairs as a document of KEY=value lines. The values which can not be written bare
+// are double quoted by Go syntax.
+func FormatKeyValues(pairs ...KeyValue) string {
+	buf := bytes.NewBuffer(make([]byte, 0, 4096))
+	for _, pair := range pairs {
+		buf.WriteString(pair.Key)
+		buf.WriteByte('=')
+		if needsKeyValueQuote(pair.Value) {
+			buf.WriteString(strconv.Quote(pair.Value))
+		} else {
+			buf.WriteString(pair.Value)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+func needsKeyValueQuote(value string) bool {
+	if value == "" {
+		return false
+	}
+	if strings.TrimSpace(value) != value || value[0] == '"' || value[0] == '\'' {
+		return true
+	}
+	for _, r := range value {
+		if r == '#' || r == '\\' || !strconv.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func getStructPtr(val reflect.Value) (reflect.Value, error) {
+	if val.Type().Kind() != reflect.Ptr {
+		if !val.CanAddr() {
+			return reflect.Value{}, ErrCanNotGetAddr
+		}
+		val = val.Addr()
+	}
+	if val.IsNil() {
+		return reflect.Value{}, ErrNilPointer
+	}
+	if val.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, ErrValueMustBeStruct
+	}
+	return val, nil
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
@@ -0,0 +1,133 @@
+package xstrings
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type keyValueDatabase struct {
+	Host string
+	Port int
+}
+
+type keyValueEmbedded struct {
+	Debug bool
+}
+
+type keyValueConfig struct {
+	Name     string `kv:"NAME"`
+	Timeout  time.Duration
+	Tags     []string `xstrings:"sep=,"`
+	Skipped  string   `kv:"-"`
+	Database keyValueDatabase
+	Cache    *keyValueDatabase
+	keyValueEmbedded
+}
+
+func TestKeyValueStruct(t *testing.T) {
+	k := &KeyValueStruct{FieldTagKey: "kv", FieldNameFold: true}
+	in := "# comment\n" +
+		"NAME=app\n" +
+		"export\tTIMEOUT = 5s\r\n" +
+		"tags: 'a,b'\n" +
+		"Skipped=x\n" +
+		"DATABASE_HOST=\"local\\thost\"\n" +
+		"database_port=5432\n" +
+		"Debug=true\n" +
+		"Unknown=1\n"
+	var x keyValueConfig
+	if err := k.Decode(strings.NewReader(in), &x); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := keyValueConfig{
+		Name:             "app",
+		Timeout:          5 * time.Second,
+		Tags:             []string{"a", "b"},
+		Database:         keyValueDatabase{"local\thost", 5432},
+		keyValueEmbedded: keyValueEmbedded{true},
+	}
+	if !reflect.DeepEqual(x, want) {
+		t.Errorf("Decode = %+v, want %+v", x, want)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := k.Encode(buf, &x); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	wantOut := "NAME=app\nTimeout=5s\nTags=a,b\nDatabase_Host=\"local\\thost\"\nDatabase_Port=5432\nDebug=true\n"
+	if got := buf.String(); got != wantOut {
+		t.Errorf("Encode = %q, want %q", got, wantOut)
+	}
+
+	if err := k.Unmarshal(&x, KeyValue{"Cache_Port", "6379"}); err != nil || x.Cache == nil || x.Cache.Port != 6379 {
+		t.Errorf("Unmarshal of nil nested struct = %+v, %v", x.Cache, err)
+	}
+	var e *KeyValueParseError
+	if err := k.Unmarshal(&x, KeyValue{"Database_Port", "x"}); !errors.As(err, &e) || e.Key() != "Database_Port" {
+		t.Errorf("Unmarshal error = %v, want *KeyValueParseError of Database_Port", err)
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []KeyValue
+	}{
+		{"A=1\nB = 2 \n", []KeyValue{{"A", "1"}, {"B", "2"}}},
+		{"export A=1\nexport\tB=2\nexport C=3\n", []KeyValue{{"A", "1"}, {"B", "2"}, {"C", "3"}}},
+		{"export\u00a0A=1\n", []KeyValue{{"A", "1"}}},
+		{"exportA=1\n", []KeyValue{{"exportA", "1"}}},
+		{"export=1\n", []KeyValue{{"export", "1"}}},
+		{"A:1\n! comment\n\n  # comment\n", []KeyValue{{"A", "1"}}},
+		{"A=\"x\\ny\"\nB='x\\ny'\nC=\n", []KeyValue{{"A", "x\ny"}, {"B", "x\\ny"}, {"C", ""}}},
+	}
+	for _, c := range cases {
+		got, err := ParseKeyValues(c.in)
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseKeyValues(%q) = %q, %v, want %q", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestParseKeyValuesError(t *testing.T) {
+	cases := []struct {
+		in           string
+		err          error
+		line, column int
+	}{
+		{"A=1\n  B\n", ErrMissingKeyValueSep, 2, 3},
+		{"A='x\n", ErrUnterminatedQuote, 1, 3},
+		{"A=1\nexport B=\"x\n", nil, 2, 10},
+	}
+	for _, c := range cases {
+		_, err := ParseKeyValues(c.in)
+		var e *ParseError
+		if !errors.As(err, &e) {
+			t.Errorf("ParseKeyValues(%q) error = %v, want *ParseError", c.in, err)
+			continue
+		}
+		if c.err != nil && !errors.Is(err, c.err) {
+			t.Errorf("ParseKeyValues(%q) error = %v, want %v", c.in, err, c.err)
+		}
+		if e.Line() != c.line || e.Column() != c.column {
+			t.Errorf("ParseKeyValues(%q) error position = %d, %d, want %d, %d", c.in, e.Line(), e.Column(), c.line, c.column)
+		}
+	}
+}
+
+func TestFormatKeyValues(t *testing.T) {
+	pairs := []KeyValue{{"A", "1"}, {"B", ""}, {"C", " x"}, {"D", "#x"}, {"E", "'x'"}, {"F", "a\nb"}}
+	str := FormatKeyValues(pairs...)
+	want := "A=1\nB=\nC=\" x\"\nD=\"#x\"\nE=\"'x'\"\nF=\"a\\nb\"\n"
+	if str != want {
+		t.Errorf("FormatKeyValues = %q, want %q", str, want)
+	}
+	got, err := ParseKeyValues(str)
+	if err != nil || !reflect.DeepEqual(got, pairs) {
+		t.Errorf("ParseKeyValues(%q) = %q, %v, want %q", str, got, err, pairs)
+	}
+}
@@ -16,6 +16,9 @@ type Marshaler struct {
 
 	TimeLayout string
 
+	// TimeUnix formats time.Time as Unix time in the unit s, ms, us or ns instead of TimeLayout, if it isn't empty.
+	TimeUnix string
+
 	FloatFmt  byte
 	FloatPrec int
 
@@ -25,6 +28,14 @@ type Marshaler struct {
 	Indent          string
 	MultiLinePrefix string
 
+	// CollectionSep enables formatting arrays, slices and maps as delimited collections instead of JSON,
+	// e.g. "a,b,c" or "k1=v1;k2=v2". It separates the elements, which are formatted by the marshaler recursively.
+	// KeyValueSep separates the keys and values of maps. If KeyValueSep is empty, DefaultKeyValueSep is used.
+	// The runes of the separators and backslashes in the elements are escaped by backslash.
+	// A single empty element is formatted as a lone backslash, because an empty string is an empty collection.
+	CollectionSep string
+	KeyValueSep   string
+
 	FuncFormatBool     func(v bool) string
 	FuncFormatInt      func(v int64) string
 	FuncFormatUint     func(v uint64) string
@@ -33,6 +44,9 @@ type Marshaler struct {
 	FuncFormatTime     func(v time.Time) string
 	FuncFormatDuration func(v time.Duration) string
 	FuncMarshalData    func(v interface{}) (string, error)
+
+	// Codecs is consulted before the built-in handling of types, if it isn't nil.
+	Codecs *CodecRegistry
 }
 
 func NewMarshaler() *Marshaler {
@@ -99,9 +113,24 @@ func (m *Marshaler) MarshalByValue(val reflect.Value) (string, error) {
 	}
 	ifc := val.Interface()
 
+	if m.Codecs != nil {
+		if encode, ok := m.Codecs.Encoder(typ); ok {
+			str, err = encode(val)
+			if err != nil {
+				return "", newFormatError(err)
+			}
+			return str, nil
+		}
+	}
+
 	if t, ok := ifc.(time.Time); ok {
 		if m.FuncFormatTime != nil {
 			str = m.FuncFormatTime(t)
+		} else if m.TimeUnix != "" {
+			str, err = formatUnixTime(t, m.TimeUnix)
+			if err != nil {
+				return "", newFormatError(err)
+			}
 		} else {
 			str = t.Format(timeLayout)
 		}
@@ -267,7 +296,9 @@ func (m *Marshaler) MarshalByValue(val reflect.Value) (string, error) {
 	case reflect.Slice:
 		fallthrough
 	case reflect.Struct:
-		if m.FuncMarshalData != nil {
+		if kind != reflect.Struct && m.CollectionSep != "" {
+			str, err = m.marshalCollection(val)
+		} else if m.FuncMarshalData != nil {
 			str, err = m.FuncMarshalData(dataVal)
 		} else {
 			var data []byte
@@ -15,6 +15,14 @@ type Unmarshaler struct {
 
 	TimeLayout string
 
+	// TimeUnix parses time.Time as Unix time in the unit s, ms, us or ns instead of TimeLayout, if it isn't empty.
+	TimeUnix string
+
+	// CollectionSep enables parsing arrays, slices and maps as delimited collections instead of JSON.
+	// See Marshaler.CollectionSep.
+	CollectionSep string
+	KeyValueSep   string
+
 	FuncParseBool     func(str string) (bool, error)
 	FuncParseInt      func(str string) (int64, error)
 	FuncParseUint     func(str string) (uint64, error)
@@ -23,6 +31,9 @@ type Unmarshaler struct {
 	FuncParseTime     func(str string) (time.Time, error)
 	FuncParseDuration func(str string) (time.Duration, error)
 	FuncUnmarshalData func(str string, ifc interface{}) error
+
+	// Codecs is consulted before the built-in handling of types, if it isn't nil.
+	Codecs *CodecRegistry
 }
 
 func NewUnmarshaler() *Unmarshaler {
@@ -75,12 +86,23 @@ func (u *Unmarshaler) UnmarshalByValue(str string, val reflect.Value) (err error
 		typ = val.Type()
 	}
 
+	if u.Codecs != nil {
+		if decode, ok := u.Codecs.Decoder(typ); ok {
+			if err = decode(str, val); err != nil {
+				return newParseError(err)
+			}
+			return nil
+		}
+	}
+
 	ifc := v.Interface()
 
 	if t, ok := ifc.(*time.Time); ok {
 		var t2 time.Time
 		if u.FuncParseTime != nil {
 			t2, err = u.FuncParseTime(str)
+		} else if u.TimeUnix != "" {
+			t2, err = parseUnixTime(str, u.TimeUnix)
 		} else {
 			t2, err = time.ParseInLocation(timeLayout, str, time.Local)
 		}
@@ -167,7 +189,12 @@ func (u *Unmarshaler) UnmarshalByValue(str string, val reflect.Value) (err error
 		if u.FuncParseUint != nil {
 			x, err = u.FuncParseUint(str)
 		} else {
-			x, err = strconv.ParseUint(str, 10, 64)
+			uintBase := intBase
+			if u.IntBase == 0 {
+				// zero IntBase parses uints in base 10 as before, instead of guessing the base by prefix
+				uintBase = 10
+			}
+			x, err = strconv.ParseUint(str, uintBase, 64)
 		}
 		if err != nil {
 			break
@@ -216,7 +243,9 @@ func (u *Unmarshaler) UnmarshalByValue(str string, val reflect.Value) (err error
 	case reflect.Slice:
 		fallthrough
 	case reflect.Struct:
-		if u.FuncUnmarshalData != nil {
+		if typ.Kind() != reflect.Struct && u.CollectionSep != "" {
+			err = u.unmarshalCollection(str, val)
+		} else if u.FuncUnmarshalData != nil {
 			err = u.FuncUnmarshalData(str, ifc)
 		} else {
 			err = json.Unmarshal([]byte(str), ifc)
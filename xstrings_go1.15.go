@@ -1,3 +1,4 @@
+//go:build go1.15
 // +build go1.15
 
 package xstrings
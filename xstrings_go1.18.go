@@ -0,0 +1,47 @@
+//go:build go1.18
+// +build go1.18
+
+package xstrings
+
+// Parse parses str into a value of type T by u. If u is nil, NewUnmarshaler() is used.
+func Parse[T any](u *Unmarshaler, str string) (T, error) {
+	if u == nil {
+		u = NewUnmarshaler()
+	}
+	var result T
+	if err := u.Unmarshal(str, &result); err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// MustParse is similar to Parse, but panics on error.
+func MustParse[T any](u *Unmarshaler, str string) T {
+	result, err := Parse[T](u, str)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// ParseSlice parses each of strs into a value of type T by u. If u is nil, NewUnmarshaler() is used.
+func ParseSlice[T any](u *Unmarshaler, strs ...string) ([]T, error) {
+	result := make([]T, 0, len(strs))
+	for _, str := range strs {
+		x, err := Parse[T](u, str)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, x)
+	}
+	return result, nil
+}
+
+// Format formats v by m. If m is nil, NewMarshaler() is used.
+func Format[T any](m *Marshaler, v T) (string, error) {
+	if m == nil {
+		m = NewMarshaler()
+	}
+	return m.Marshal(v)
+}
@@ -0,0 +1,104 @@
+//go:build go1.18
+// +build go1.18
+
+package xstrings
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func checkParse[T any](t *testing.T, u *Unmarshaler, str string) {
+	t.Helper()
+	got, err := Parse[T](u, str)
+
+	var want T
+	u2 := u
+	if u2 == nil {
+		u2 = NewUnmarshaler()
+	}
+	wantErr := u2.Unmarshal(str, &want)
+	if (err == nil) != (wantErr == nil) || (err != nil && err.Error() != wantErr.Error()) {
+		t.Errorf("Parse[%T](%q) error = %v, want %v", want, str, err, wantErr)
+		return
+	}
+	if err != nil {
+		var zero T
+		want = zero
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse[%T](%q) = %v, want %v", want, str, got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	hex := &Unmarshaler{IntBase: 16}
+	checkParse[int](t, nil, "42")
+	checkParse[int](t, nil, "x")
+	checkParse[int8](t, hex, "7f")
+	checkParse[uint](t, nil, "-1")
+	checkParse[float64](t, nil, "1.5")
+	checkParse[bool](t, nil, "true")
+	checkParse[string](t, nil, "a b")
+	checkParse[*int](t, nil, "")
+	checkParse[*int](t, nil, "3")
+	checkParse[time.Duration](t, nil, "1m30s")
+	checkParse[time.Time](t, nil, "2006-01-02T15:04:05Z")
+	checkParse[[]int](t, nil, "[1,2]")
+	checkParse[[]int](t, &Unmarshaler{CollectionSep: ","}, "1,2")
+	checkParse[map[string]int](t, nil, `{"a":1}`)
+}
+
+func TestMustParse(t *testing.T) {
+	if got := MustParse[int](nil, "42"); got != 42 {
+		t.Errorf("MustParse = %d, want 42", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustParse of invalid string doesn't panic")
+		}
+	}()
+	MustParse[int](nil, "x")
+}
+
+func TestParseSlice(t *testing.T) {
+	got, err := ParseSlice[int](&Unmarshaler{IntBase: 16}, "a", "10")
+	if err != nil || !reflect.DeepEqual(got, []int{10, 16}) {
+		t.Errorf("ParseSlice = %v, %v, want [10 16]", got, err)
+	}
+	got, err = ParseSlice[int](nil)
+	if err != nil || got == nil || len(got) != 0 {
+		t.Errorf("ParseSlice() = %#v, %v, want empty slice", got, err)
+	}
+	if _, err := ParseSlice[int](nil, "1", "x"); err == nil {
+		t.Errorf("ParseSlice of invalid string expected error")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	hex := &Marshaler{IntBase: 16}
+	cases := []struct {
+		m   *Marshaler
+		val interface{}
+		got func() (string, error)
+	}{
+		{nil, 42, func() (string, error) { return Format[int](nil, 42) }},
+		{hex, 255, func() (string, error) { return Format[int](hex, 255) }},
+		{nil, 1.5, func() (string, error) { return Format[float64](nil, 1.5) }},
+		{nil, (*int)(nil), func() (string, error) { return Format[*int](nil, nil) }},
+		{nil, time.Minute, func() (string, error) { return Format[time.Duration](nil, time.Minute) }},
+		{nil, []int{1, 2}, func() (string, error) { return Format[[]int](nil, []int{1, 2}) }},
+	}
+	for _, c := range cases {
+		m := c.m
+		if m == nil {
+			m = NewMarshaler()
+		}
+		want, wantErr := m.Marshal(c.val)
+		got, err := c.got()
+		if got != want || (err == nil) != (wantErr == nil) {
+			t.Errorf("Format(%#v) = %q, %v, want %q, %v", c.val, got, err, want, wantErr)
+		}
+	}
+}